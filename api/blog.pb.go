@@ -11,6 +11,7 @@ import (
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -23,14 +24,74 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type FeedEvent_Type int32
+
+const (
+	FeedEvent_TYPE_UNSPECIFIED FeedEvent_Type = 0
+	FeedEvent_HEARTBEAT        FeedEvent_Type = 1
+	FeedEvent_POST_CREATED     FeedEvent_Type = 2
+	FeedEvent_POST_UPDATED     FeedEvent_Type = 3
+	FeedEvent_POST_DELETED     FeedEvent_Type = 4
+	FeedEvent_LIKES_CHANGED    FeedEvent_Type = 5
+)
+
+// Enum value maps for FeedEvent_Type.
+var (
+	FeedEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "HEARTBEAT",
+		2: "POST_CREATED",
+		3: "POST_UPDATED",
+		4: "POST_DELETED",
+		5: "LIKES_CHANGED",
+	}
+	FeedEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"HEARTBEAT":        1,
+		"POST_CREATED":     2,
+		"POST_UPDATED":     3,
+		"POST_DELETED":     4,
+		"LIKES_CHANGED":    5,
+	}
+)
+
+func (x FeedEvent_Type) Enum() *FeedEvent_Type {
+	p := new(FeedEvent_Type)
+	*p = x
+	return p
+}
+
+func (x FeedEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FeedEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_blog_proto_enumTypes[0].Descriptor()
+}
+
+func (FeedEvent_Type) Type() protoreflect.EnumType {
+	return &file_blog_proto_enumTypes[0]
+}
+
+func (x FeedEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FeedEvent_Type.Descriptor instead.
+func (FeedEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{21, 0}
+}
+
 type Post struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Author        *User                  `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
-	Body          string                 `protobuf:"bytes,3,opt,name=body,proto3" json:"body,omitempty"`
-	CreatedAt     string                 `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
-	LikesCount    int32                  `protobuf:"varint,5,opt,name=likes_count,json=likesCount,proto3" json:"likes_count,omitempty"`
-	IsLiked       bool                   `protobuf:"varint,6,opt,name=is_liked,json=isLiked,proto3" json:"is_liked,omitempty"`
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Author     *User                  `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
+	Body       string                 `protobuf:"bytes,3,opt,name=body,proto3" json:"body,omitempty"`
+	CreatedAt  string                 `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	LikesCount int32                  `protobuf:"varint,5,opt,name=likes_count,json=likesCount,proto3" json:"likes_count,omitempty"`
+	IsLiked    bool                   `protobuf:"varint,6,opt,name=is_liked,json=isLiked,proto3" json:"is_liked,omitempty"`
+	// Set while likes can't be read, likes_count and is_liked are then unset.
+	LikesUnknown  bool `protobuf:"varint,7,opt,name=likes_unknown,json=likesUnknown,proto3" json:"likes_unknown,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -107,6 +168,13 @@ func (x *Post) GetIsLiked() bool {
 	return false
 }
 
+func (x *Post) GetLikesUnknown() bool {
+	if x != nil {
+		return x.LikesUnknown
+	}
+	return false
+}
+
 type User struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -352,9 +420,13 @@ func (x *CreatePostResponse) GetPost() *Post {
 }
 
 type UpdatePostRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Body          string                 `protobuf:"bytes,2,opt,name=body,proto3" json:"body,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Body  string                 `protobuf:"bytes,2,opt,name=body,proto3" json:"body,omitempty"`
+	// Post fields to update, e.g. "body". An empty mask replaces every
+	// updatable field. Immutable fields (id, author, created_at, likes_count,
+	// is_liked) and unknown fields are rejected with INVALID_ARGUMENT.
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,3,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -403,6 +475,13 @@ func (x *UpdatePostRequest) GetBody() string {
 	return ""
 }
 
+func (x *UpdatePostRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
 type UpdatePostResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
@@ -615,12 +694,526 @@ func (x *ToggleLikeResponse) GetPost() *Post {
 	return nil
 }
 
+// Likes the post. Liking an already liked post changes nothing, so the call
+// is safe to retry.
+type LikePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LikePostRequest) Reset() {
+	*x = LikePostRequest{}
+	mi := &file_blog_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LikePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LikePostRequest) ProtoMessage() {}
+
+func (x *LikePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LikePostRequest.ProtoReflect.Descriptor instead.
+func (*LikePostRequest) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *LikePostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type LikePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LikePostResponse) Reset() {
+	*x = LikePostResponse{}
+	mi := &file_blog_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LikePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LikePostResponse) ProtoMessage() {}
+
+func (x *LikePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LikePostResponse.ProtoReflect.Descriptor instead.
+func (*LikePostResponse) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *LikePostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+// Removes the like from the post. Safe to retry as well.
+type UnlikePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlikePostRequest) Reset() {
+	*x = UnlikePostRequest{}
+	mi := &file_blog_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlikePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlikePostRequest) ProtoMessage() {}
+
+func (x *UnlikePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlikePostRequest.ProtoReflect.Descriptor instead.
+func (*UnlikePostRequest) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UnlikePostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type UnlikePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlikePostResponse) Reset() {
+	*x = UnlikePostResponse{}
+	mi := &file_blog_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlikePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlikePostResponse) ProtoMessage() {}
+
+func (x *UnlikePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlikePostResponse.ProtoReflect.Descriptor instead.
+func (*UnlikePostResponse) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UnlikePostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+type ListLikersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLikersRequest) Reset() {
+	*x = ListLikersRequest{}
+	mi := &file_blog_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLikersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLikersRequest) ProtoMessage() {}
+
+func (x *ListLikersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLikersRequest.ProtoReflect.Descriptor instead.
+func (*ListLikersRequest) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListLikersRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *ListLikersRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListLikersRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// Users who liked the post, most recent like first.
+type ListLikersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Users         []*User                `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLikersResponse) Reset() {
+	*x = ListLikersResponse{}
+	mi := &file_blog_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLikersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLikersResponse) ProtoMessage() {}
+
+func (x *ListLikersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLikersResponse.ProtoReflect.Descriptor instead.
+func (*ListLikersResponse) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ListLikersResponse) GetUsers() []*User {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+type ListLikedPostsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLikedPostsRequest) Reset() {
+	*x = ListLikedPostsRequest{}
+	mi := &file_blog_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLikedPostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLikedPostsRequest) ProtoMessage() {}
+
+func (x *ListLikedPostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLikedPostsRequest.ProtoReflect.Descriptor instead.
+func (*ListLikedPostsRequest) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListLikedPostsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListLikedPostsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListLikedPostsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// Posts liked by the user, most recently liked first.
+type ListLikedPostsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Posts         []*Post                `protobuf:"bytes,1,rep,name=posts,proto3" json:"posts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLikedPostsResponse) Reset() {
+	*x = ListLikedPostsResponse{}
+	mi := &file_blog_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLikedPostsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLikedPostsResponse) ProtoMessage() {}
+
+func (x *ListLikedPostsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLikedPostsResponse.ProtoReflect.Descriptor instead.
+func (*ListLikedPostsResponse) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListLikedPostsResponse) GetPosts() []*Post {
+	if x != nil {
+		return x.Posts
+	}
+	return nil
+}
+
+type WatchFeedRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Id of the last event the client received. Retained events after it are
+	// sent before live ones. Fails with OUT_OF_RANGE if it is no longer retained.
+	LastEventId   string `protobuf:"bytes,1,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchFeedRequest) Reset() {
+	*x = WatchFeedRequest{}
+	mi := &file_blog_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchFeedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchFeedRequest) ProtoMessage() {}
+
+func (x *WatchFeedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchFeedRequest.ProtoReflect.Descriptor instead.
+func (*WatchFeedRequest) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *WatchFeedRequest) GetLastEventId() string {
+	if x != nil {
+		return x.LastEventId
+	}
+	return ""
+}
+
+type FeedEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Empty for heartbeats.
+	Id     string         `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type   FeedEvent_Type `protobuf:"varint,2,opt,name=type,proto3,enum=blog.FeedEvent_Type" json:"type,omitempty"`
+	PostId string         `protobuf:"bytes,3,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	// Set for POST_CREATED and POST_UPDATED, is_liked is always false.
+	Post *Post `protobuf:"bytes,4,opt,name=post,proto3" json:"post,omitempty"`
+	// Set for LIKES_CHANGED.
+	LikesCount    int32 `protobuf:"varint,5,opt,name=likes_count,json=likesCount,proto3" json:"likes_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FeedEvent) Reset() {
+	*x = FeedEvent{}
+	mi := &file_blog_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeedEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeedEvent) ProtoMessage() {}
+
+func (x *FeedEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeedEvent.ProtoReflect.Descriptor instead.
+func (*FeedEvent) Descriptor() ([]byte, []int) {
+	return file_blog_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *FeedEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *FeedEvent) GetType() FeedEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return FeedEvent_TYPE_UNSPECIFIED
+}
+
+func (x *FeedEvent) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *FeedEvent) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+func (x *FeedEvent) GetLikesCount() int32 {
+	if x != nil {
+		return x.LikesCount
+	}
+	return 0
+}
+
 var File_blog_proto protoreflect.FileDescriptor
 
 const file_blog_proto_rawDesc = "" +
 	"\n" +
 	"\n" +
-	"blog.proto\x12\x04blog\x1a\x1cgoogle/api/annotations.proto\x1a.protoc-gen-openapiv2/options/annotations.proto\"\xa9\x01\n" +
+	"blog.proto\x12\x04blog\x1a\x1cgoogle/api/annotations.proto\x1a google/protobuf/field_mask.proto\x1a.protoc-gen-openapiv2/options/annotations.proto\"\xce\x01\n" +
 	"\x04Post\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\"\n" +
 	"\x06author\x18\x02 \x01(\v2\n" +
@@ -630,7 +1223,8 @@ const file_blog_proto_rawDesc = "" +
 	"created_at\x18\x04 \x01(\tR\tcreatedAt\x12\x1f\n" +
 	"\vlikes_count\x18\x05 \x01(\x05R\n" +
 	"likesCount\x12\x19\n" +
-	"\bis_liked\x18\x06 \x01(\bR\aisLiked\"P\n" +
+	"\bis_liked\x18\x06 \x01(\bR\aisLiked\x12#\n" +
+	"\rlikes_unknown\x18\a \x01(\bR\flikesUnknown\"P\n" +
 	"\x04User\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
 	"\tnick_name\x18\x02 \x01(\tR\bnickName\x12\x1b\n" +
@@ -645,10 +1239,12 @@ const file_blog_proto_rawDesc = "" +
 	"\x04body\x18\x01 \x01(\tR\x04body\"4\n" +
 	"\x12CreatePostResponse\x12\x1e\n" +
 	"\x04post\x18\x01 \x01(\v2\n" +
-	".blog.PostR\x04post\"7\n" +
+	".blog.PostR\x04post\"t\n" +
 	"\x11UpdatePostRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
-	"\x04body\x18\x02 \x01(\tR\x04body\"4\n" +
+	"\x04body\x18\x02 \x01(\tR\x04body\x12;\n" +
+	"\vupdate_mask\x18\x03 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\"4\n" +
 	"\x12UpdatePostResponse\x12\x1e\n" +
 	"\x04post\x18\x01 \x01(\v2\n" +
 	".blog.PostR\x04post\"#\n" +
@@ -659,7 +1255,49 @@ const file_blog_proto_rawDesc = "" +
 	"\apost_id\x18\x01 \x01(\tR\x06postId\"4\n" +
 	"\x12ToggleLikeResponse\x12\x1e\n" +
 	"\x04post\x18\x01 \x01(\v2\n" +
-	".blog.PostR\x04post2\xfc\x04\n" +
+	".blog.PostR\x04post\"*\n" +
+	"\x0fLikePostRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\"2\n" +
+	"\x10LikePostResponse\x12\x1e\n" +
+	"\x04post\x18\x01 \x01(\v2\n" +
+	".blog.PostR\x04post\",\n" +
+	"\x11UnlikePostRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\"4\n" +
+	"\x12UnlikePostResponse\x12\x1e\n" +
+	"\x04post\x18\x01 \x01(\v2\n" +
+	".blog.PostR\x04post\"Z\n" +
+	"\x11ListLikersRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\"6\n" +
+	"\x12ListLikersResponse\x12 \n" +
+	"\x05users\x18\x01 \x03(\v2\n" +
+	".blog.UserR\x05users\"^\n" +
+	"\x15ListLikedPostsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\":\n" +
+	"\x16ListLikedPostsResponse\x12 \n" +
+	"\x05posts\x18\x01 \x03(\v2\n" +
+	".blog.PostR\x05posts\"6\n" +
+	"\x10WatchFeedRequest\x12\"\n" +
+	"\rlast_event_id\x18\x01 \x01(\tR\vlastEventId\"\x95\x02\n" +
+	"\tFeedEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x14.blog.FeedEvent.TypeR\x04type\x12\x17\n" +
+	"\apost_id\x18\x03 \x01(\tR\x06postId\x12\x1e\n" +
+	"\x04post\x18\x04 \x01(\v2\n" +
+	".blog.PostR\x04post\x12\x1f\n" +
+	"\vlikes_count\x18\x05 \x01(\x05R\n" +
+	"likesCount\"t\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\r\n" +
+	"\tHEARTBEAT\x10\x01\x12\x10\n" +
+	"\fPOST_CREATED\x10\x02\x12\x10\n" +
+	"\fPOST_UPDATED\x10\x03\x12\x10\n" +
+	"\fPOST_DELETED\x10\x04\x12\x11\n" +
+	"\rLIKES_CHANGED\x10\x052\xaa\n" +
+	"\n" +
 	"\vBlogService\x12n\n" +
 	"\bGetPosts\x12\x15.blog.GetPostsRequest\x1a\x16.blog.GetPostsResponse\"3\x92A\x1fr\x1d\n" +
 	"\x1b\n" +
@@ -667,11 +1305,11 @@ const file_blog_proto_rawDesc = "" +
 	"\n" +
 	"CreatePost\x12\x17.blog.CreatePostRequest\x1a\x18.blog.CreatePostResponse\"6\x92A\x1fr\x1d\n" +
 	"\x1b\n" +
-	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v1/posts\x12|\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v1/posts\x12\x91\x01\n" +
 	"\n" +
-	"UpdatePost\x12\x17.blog.UpdatePostRequest\x1a\x18.blog.UpdatePostResponse\";\x92A\x1fr\x1d\n" +
+	"UpdatePost\x12\x17.blog.UpdatePostRequest\x1a\x18.blog.UpdatePostResponse\"P\x92A\x1fr\x1d\n" +
 	"\x1b\n" +
-	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x13:\x01*\x1a\x0e/v1/posts/{id}\x12y\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02(:\x01*Z\x13:\x01*2\x0e/v1/posts/{id}\x1a\x0e/v1/posts/{id}\x12y\n" +
 	"\n" +
 	"DeletePost\x12\x17.blog.DeletePostRequest\x1a\x18.blog.DeletePostResponse\"8\x92A\x1fr\x1d\n" +
 	"\x1b\n" +
@@ -679,7 +1317,24 @@ const file_blog_proto_rawDesc = "" +
 	"\n" +
 	"ToggleLike\x12\x17.blog.ToggleLikeRequest\x1a\x18.blog.ToggleLikeResponse\"I\x92A\x1fr\x1d\n" +
 	"\x1b\n" +
-	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02!\"\x1f/v1/posts/{post_id}/toggle_likeB\x17Z\x15go_grpc_blog/api/blogb\x06proto3"
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02!\"\x1f/v1/posts/{post_id}/toggle_like\x12}\n" +
+	"\bLikePost\x12\x15.blog.LikePostRequest\x1a\x16.blog.LikePostResponse\"B\x92A\x1fr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x1a\x1a\x18/v1/posts/{post_id}/like\x12\x83\x01\n" +
+	"\n" +
+	"UnlikePost\x12\x17.blog.UnlikePostRequest\x1a\x18.blog.UnlikePostResponse\"B\x92A\x1fr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x1a*\x18/v1/posts/{post_id}/like\x12\x85\x01\n" +
+	"\n" +
+	"ListLikers\x12\x17.blog.ListLikersRequest\x1a\x18.blog.ListLikersResponse\"D\x92A\x1fr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x1c\x12\x1a/v1/posts/{post_id}/likers\x12\x96\x01\n" +
+	"\x0eListLikedPosts\x12\x1b.blog.ListLikedPostsRequest\x1a\x1c.blog.ListLikedPostsResponse\"I\x92A\x1fr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02!\x12\x1f/v1/users/{user_id}/liked_posts\x12p\n" +
+	"\tWatchFeed\x12\x16.blog.WatchFeedRequest\x1a\x0f.blog.FeedEvent\"8\x92A\x1fr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x10\x12\x0e/v1/feed/watch0\x01B\x17Z\x15go_grpc_blog/api/blogb\x06proto3"
 
 var (
 	file_blog_proto_rawDescOnce sync.Once
@@ -693,42 +1348,72 @@ func file_blog_proto_rawDescGZIP() []byte {
 	return file_blog_proto_rawDescData
 }
 
-var file_blog_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_blog_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_blog_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
 var file_blog_proto_goTypes = []any{
-	(*Post)(nil),               // 0: blog.Post
-	(*User)(nil),               // 1: blog.User
-	(*GetPostsRequest)(nil),    // 2: blog.GetPostsRequest
-	(*GetPostsResponse)(nil),   // 3: blog.GetPostsResponse
-	(*CreatePostRequest)(nil),  // 4: blog.CreatePostRequest
-	(*CreatePostResponse)(nil), // 5: blog.CreatePostResponse
-	(*UpdatePostRequest)(nil),  // 6: blog.UpdatePostRequest
-	(*UpdatePostResponse)(nil), // 7: blog.UpdatePostResponse
-	(*DeletePostRequest)(nil),  // 8: blog.DeletePostRequest
-	(*DeletePostResponse)(nil), // 9: blog.DeletePostResponse
-	(*ToggleLikeRequest)(nil),  // 10: blog.ToggleLikeRequest
-	(*ToggleLikeResponse)(nil), // 11: blog.ToggleLikeResponse
+	(FeedEvent_Type)(0),            // 0: blog.FeedEvent.Type
+	(*Post)(nil),                   // 1: blog.Post
+	(*User)(nil),                   // 2: blog.User
+	(*GetPostsRequest)(nil),        // 3: blog.GetPostsRequest
+	(*GetPostsResponse)(nil),       // 4: blog.GetPostsResponse
+	(*CreatePostRequest)(nil),      // 5: blog.CreatePostRequest
+	(*CreatePostResponse)(nil),     // 6: blog.CreatePostResponse
+	(*UpdatePostRequest)(nil),      // 7: blog.UpdatePostRequest
+	(*UpdatePostResponse)(nil),     // 8: blog.UpdatePostResponse
+	(*DeletePostRequest)(nil),      // 9: blog.DeletePostRequest
+	(*DeletePostResponse)(nil),     // 10: blog.DeletePostResponse
+	(*ToggleLikeRequest)(nil),      // 11: blog.ToggleLikeRequest
+	(*ToggleLikeResponse)(nil),     // 12: blog.ToggleLikeResponse
+	(*LikePostRequest)(nil),        // 13: blog.LikePostRequest
+	(*LikePostResponse)(nil),       // 14: blog.LikePostResponse
+	(*UnlikePostRequest)(nil),      // 15: blog.UnlikePostRequest
+	(*UnlikePostResponse)(nil),     // 16: blog.UnlikePostResponse
+	(*ListLikersRequest)(nil),      // 17: blog.ListLikersRequest
+	(*ListLikersResponse)(nil),     // 18: blog.ListLikersResponse
+	(*ListLikedPostsRequest)(nil),  // 19: blog.ListLikedPostsRequest
+	(*ListLikedPostsResponse)(nil), // 20: blog.ListLikedPostsResponse
+	(*WatchFeedRequest)(nil),       // 21: blog.WatchFeedRequest
+	(*FeedEvent)(nil),              // 22: blog.FeedEvent
+	(*fieldmaskpb.FieldMask)(nil),  // 23: google.protobuf.FieldMask
 }
 var file_blog_proto_depIdxs = []int32{
-	1,  // 0: blog.Post.author:type_name -> blog.User
-	0,  // 1: blog.GetPostsResponse.posts:type_name -> blog.Post
-	0,  // 2: blog.CreatePostResponse.post:type_name -> blog.Post
-	0,  // 3: blog.UpdatePostResponse.post:type_name -> blog.Post
-	0,  // 4: blog.ToggleLikeResponse.post:type_name -> blog.Post
-	2,  // 5: blog.BlogService.GetPosts:input_type -> blog.GetPostsRequest
-	4,  // 6: blog.BlogService.CreatePost:input_type -> blog.CreatePostRequest
-	6,  // 7: blog.BlogService.UpdatePost:input_type -> blog.UpdatePostRequest
-	8,  // 8: blog.BlogService.DeletePost:input_type -> blog.DeletePostRequest
-	10, // 9: blog.BlogService.ToggleLike:input_type -> blog.ToggleLikeRequest
-	3,  // 10: blog.BlogService.GetPosts:output_type -> blog.GetPostsResponse
-	5,  // 11: blog.BlogService.CreatePost:output_type -> blog.CreatePostResponse
-	7,  // 12: blog.BlogService.UpdatePost:output_type -> blog.UpdatePostResponse
-	9,  // 13: blog.BlogService.DeletePost:output_type -> blog.DeletePostResponse
-	11, // 14: blog.BlogService.ToggleLike:output_type -> blog.ToggleLikeResponse
-	10, // [10:15] is the sub-list for method output_type
-	5,  // [5:10] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+	2,  // 0: blog.Post.author:type_name -> blog.User
+	1,  // 1: blog.GetPostsResponse.posts:type_name -> blog.Post
+	1,  // 2: blog.CreatePostResponse.post:type_name -> blog.Post
+	23, // 3: blog.UpdatePostRequest.update_mask:type_name -> google.protobuf.FieldMask
+	1,  // 4: blog.UpdatePostResponse.post:type_name -> blog.Post
+	1,  // 5: blog.ToggleLikeResponse.post:type_name -> blog.Post
+	1,  // 6: blog.LikePostResponse.post:type_name -> blog.Post
+	1,  // 7: blog.UnlikePostResponse.post:type_name -> blog.Post
+	2,  // 8: blog.ListLikersResponse.users:type_name -> blog.User
+	1,  // 9: blog.ListLikedPostsResponse.posts:type_name -> blog.Post
+	0,  // 10: blog.FeedEvent.type:type_name -> blog.FeedEvent.Type
+	1,  // 11: blog.FeedEvent.post:type_name -> blog.Post
+	3,  // 12: blog.BlogService.GetPosts:input_type -> blog.GetPostsRequest
+	5,  // 13: blog.BlogService.CreatePost:input_type -> blog.CreatePostRequest
+	7,  // 14: blog.BlogService.UpdatePost:input_type -> blog.UpdatePostRequest
+	9,  // 15: blog.BlogService.DeletePost:input_type -> blog.DeletePostRequest
+	11, // 16: blog.BlogService.ToggleLike:input_type -> blog.ToggleLikeRequest
+	13, // 17: blog.BlogService.LikePost:input_type -> blog.LikePostRequest
+	15, // 18: blog.BlogService.UnlikePost:input_type -> blog.UnlikePostRequest
+	17, // 19: blog.BlogService.ListLikers:input_type -> blog.ListLikersRequest
+	19, // 20: blog.BlogService.ListLikedPosts:input_type -> blog.ListLikedPostsRequest
+	21, // 21: blog.BlogService.WatchFeed:input_type -> blog.WatchFeedRequest
+	4,  // 22: blog.BlogService.GetPosts:output_type -> blog.GetPostsResponse
+	6,  // 23: blog.BlogService.CreatePost:output_type -> blog.CreatePostResponse
+	8,  // 24: blog.BlogService.UpdatePost:output_type -> blog.UpdatePostResponse
+	10, // 25: blog.BlogService.DeletePost:output_type -> blog.DeletePostResponse
+	12, // 26: blog.BlogService.ToggleLike:output_type -> blog.ToggleLikeResponse
+	14, // 27: blog.BlogService.LikePost:output_type -> blog.LikePostResponse
+	16, // 28: blog.BlogService.UnlikePost:output_type -> blog.UnlikePostResponse
+	18, // 29: blog.BlogService.ListLikers:output_type -> blog.ListLikersResponse
+	20, // 30: blog.BlogService.ListLikedPosts:output_type -> blog.ListLikedPostsResponse
+	22, // 31: blog.BlogService.WatchFeed:output_type -> blog.FeedEvent
+	22, // [22:32] is the sub-list for method output_type
+	12, // [12:22] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
 }
 
 func init() { file_blog_proto_init() }
@@ -741,13 +1426,14 @@ func file_blog_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_blog_proto_rawDesc), len(file_blog_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   12,
+			NumEnums:      1,
+			NumMessages:   22,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_blog_proto_goTypes,
 		DependencyIndexes: file_blog_proto_depIdxs,
+		EnumInfos:         file_blog_proto_enumTypes,
 		MessageInfos:      file_blog_proto_msgTypes,
 	}.Build()
 	File_blog_proto = out.File
@@ -19,11 +19,16 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BlogService_GetPosts_FullMethodName   = "/blog.BlogService/GetPosts"
-	BlogService_CreatePost_FullMethodName = "/blog.BlogService/CreatePost"
-	BlogService_UpdatePost_FullMethodName = "/blog.BlogService/UpdatePost"
-	BlogService_DeletePost_FullMethodName = "/blog.BlogService/DeletePost"
-	BlogService_ToggleLike_FullMethodName = "/blog.BlogService/ToggleLike"
+	BlogService_GetPosts_FullMethodName       = "/blog.BlogService/GetPosts"
+	BlogService_CreatePost_FullMethodName     = "/blog.BlogService/CreatePost"
+	BlogService_UpdatePost_FullMethodName     = "/blog.BlogService/UpdatePost"
+	BlogService_DeletePost_FullMethodName     = "/blog.BlogService/DeletePost"
+	BlogService_ToggleLike_FullMethodName     = "/blog.BlogService/ToggleLike"
+	BlogService_LikePost_FullMethodName       = "/blog.BlogService/LikePost"
+	BlogService_UnlikePost_FullMethodName     = "/blog.BlogService/UnlikePost"
+	BlogService_ListLikers_FullMethodName     = "/blog.BlogService/ListLikers"
+	BlogService_ListLikedPosts_FullMethodName = "/blog.BlogService/ListLikedPosts"
+	BlogService_WatchFeed_FullMethodName      = "/blog.BlogService/WatchFeed"
 )
 
 // BlogServiceClient is the client API for BlogService service.
@@ -34,7 +39,15 @@ type BlogServiceClient interface {
 	CreatePost(ctx context.Context, in *CreatePostRequest, opts ...grpc.CallOption) (*CreatePostResponse, error)
 	UpdatePost(ctx context.Context, in *UpdatePostRequest, opts ...grpc.CallOption) (*UpdatePostResponse, error)
 	DeletePost(ctx context.Context, in *DeletePostRequest, opts ...grpc.CallOption) (*DeletePostResponse, error)
+	// Deprecated: not idempotent, use LikePost and UnlikePost instead.
 	ToggleLike(ctx context.Context, in *ToggleLikeRequest, opts ...grpc.CallOption) (*ToggleLikeResponse, error)
+	LikePost(ctx context.Context, in *LikePostRequest, opts ...grpc.CallOption) (*LikePostResponse, error)
+	UnlikePost(ctx context.Context, in *UnlikePostRequest, opts ...grpc.CallOption) (*UnlikePostResponse, error)
+	ListLikers(ctx context.Context, in *ListLikersRequest, opts ...grpc.CallOption) (*ListLikersResponse, error)
+	ListLikedPosts(ctx context.Context, in *ListLikedPostsRequest, opts ...grpc.CallOption) (*ListLikedPostsResponse, error)
+	// Streams feed events as they happen. Sends a HEARTBEAT event when the feed
+	// is idle. Pass the id of the last received event to resume after a reconnect.
+	WatchFeed(ctx context.Context, in *WatchFeedRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FeedEvent], error)
 }
 
 type blogServiceClient struct {
@@ -95,6 +108,65 @@ func (c *blogServiceClient) ToggleLike(ctx context.Context, in *ToggleLikeReques
 	return out, nil
 }
 
+func (c *blogServiceClient) LikePost(ctx context.Context, in *LikePostRequest, opts ...grpc.CallOption) (*LikePostResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LikePostResponse)
+	err := c.cc.Invoke(ctx, BlogService_LikePost_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blogServiceClient) UnlikePost(ctx context.Context, in *UnlikePostRequest, opts ...grpc.CallOption) (*UnlikePostResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnlikePostResponse)
+	err := c.cc.Invoke(ctx, BlogService_UnlikePost_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blogServiceClient) ListLikers(ctx context.Context, in *ListLikersRequest, opts ...grpc.CallOption) (*ListLikersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLikersResponse)
+	err := c.cc.Invoke(ctx, BlogService_ListLikers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blogServiceClient) ListLikedPosts(ctx context.Context, in *ListLikedPostsRequest, opts ...grpc.CallOption) (*ListLikedPostsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLikedPostsResponse)
+	err := c.cc.Invoke(ctx, BlogService_ListLikedPosts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blogServiceClient) WatchFeed(ctx context.Context, in *WatchFeedRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FeedEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BlogService_ServiceDesc.Streams[0], BlogService_WatchFeed_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchFeedRequest, FeedEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlogService_WatchFeedClient = grpc.ServerStreamingClient[FeedEvent]
+
 // BlogServiceServer is the server API for BlogService service.
 // All implementations must embed UnimplementedBlogServiceServer
 // for forward compatibility.
@@ -103,7 +175,15 @@ type BlogServiceServer interface {
 	CreatePost(context.Context, *CreatePostRequest) (*CreatePostResponse, error)
 	UpdatePost(context.Context, *UpdatePostRequest) (*UpdatePostResponse, error)
 	DeletePost(context.Context, *DeletePostRequest) (*DeletePostResponse, error)
+	// Deprecated: not idempotent, use LikePost and UnlikePost instead.
 	ToggleLike(context.Context, *ToggleLikeRequest) (*ToggleLikeResponse, error)
+	LikePost(context.Context, *LikePostRequest) (*LikePostResponse, error)
+	UnlikePost(context.Context, *UnlikePostRequest) (*UnlikePostResponse, error)
+	ListLikers(context.Context, *ListLikersRequest) (*ListLikersResponse, error)
+	ListLikedPosts(context.Context, *ListLikedPostsRequest) (*ListLikedPostsResponse, error)
+	// Streams feed events as they happen. Sends a HEARTBEAT event when the feed
+	// is idle. Pass the id of the last received event to resume after a reconnect.
+	WatchFeed(*WatchFeedRequest, grpc.ServerStreamingServer[FeedEvent]) error
 	mustEmbedUnimplementedBlogServiceServer()
 }
 
@@ -129,6 +209,21 @@ func (UnimplementedBlogServiceServer) DeletePost(context.Context, *DeletePostReq
 func (UnimplementedBlogServiceServer) ToggleLike(context.Context, *ToggleLikeRequest) (*ToggleLikeResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ToggleLike not implemented")
 }
+func (UnimplementedBlogServiceServer) LikePost(context.Context, *LikePostRequest) (*LikePostResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LikePost not implemented")
+}
+func (UnimplementedBlogServiceServer) UnlikePost(context.Context, *UnlikePostRequest) (*UnlikePostResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlikePost not implemented")
+}
+func (UnimplementedBlogServiceServer) ListLikers(context.Context, *ListLikersRequest) (*ListLikersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLikers not implemented")
+}
+func (UnimplementedBlogServiceServer) ListLikedPosts(context.Context, *ListLikedPostsRequest) (*ListLikedPostsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLikedPosts not implemented")
+}
+func (UnimplementedBlogServiceServer) WatchFeed(*WatchFeedRequest, grpc.ServerStreamingServer[FeedEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchFeed not implemented")
+}
 func (UnimplementedBlogServiceServer) mustEmbedUnimplementedBlogServiceServer() {}
 func (UnimplementedBlogServiceServer) testEmbeddedByValue()                     {}
 
@@ -240,6 +335,89 @@ func _BlogService_ToggleLike_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BlogService_LikePost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LikePostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlogServiceServer).LikePost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlogService_LikePost_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlogServiceServer).LikePost(ctx, req.(*LikePostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlogService_UnlikePost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlikePostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlogServiceServer).UnlikePost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlogService_UnlikePost_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlogServiceServer).UnlikePost(ctx, req.(*UnlikePostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlogService_ListLikers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLikersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlogServiceServer).ListLikers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlogService_ListLikers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlogServiceServer).ListLikers(ctx, req.(*ListLikersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlogService_ListLikedPosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLikedPostsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlogServiceServer).ListLikedPosts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlogService_ListLikedPosts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlogServiceServer).ListLikedPosts(ctx, req.(*ListLikedPostsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlogService_WatchFeed_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchFeedRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlogServiceServer).WatchFeed(m, &grpc.GenericServerStream[WatchFeedRequest, FeedEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlogService_WatchFeedServer = grpc.ServerStreamingServer[FeedEvent]
+
 // BlogService_ServiceDesc is the grpc.ServiceDesc for BlogService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -267,7 +445,29 @@ var BlogService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ToggleLike",
 			Handler:    _BlogService_ToggleLike_Handler,
 		},
+		{
+			MethodName: "LikePost",
+			Handler:    _BlogService_LikePost_Handler,
+		},
+		{
+			MethodName: "UnlikePost",
+			Handler:    _BlogService_UnlikePost_Handler,
+		},
+		{
+			MethodName: "ListLikers",
+			Handler:    _BlogService_ListLikers_Handler,
+		},
+		{
+			MethodName: "ListLikedPosts",
+			Handler:    _BlogService_ListLikedPosts_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchFeed",
+			Handler:       _BlogService_WatchFeed_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "blog.proto",
 }
@@ -6,4 +6,6 @@ protoc \
 --go-grpc_out ./ --go-grpc_opt paths=source_relative \
 --grpc-gateway_out ./ --grpc-gateway_opt paths=source_relative \
 --openapiv2_out=allow_merge=true,merge_file_name=api:./ \
-./*.proto
\ No newline at end of file
+./*.proto ./v2/*.proto
+
+protoc -I ./ --go_out ./ --go_opt paths=source_relative ./cache/*.proto
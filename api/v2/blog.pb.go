@@ -0,0 +1,1444 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: v2/blog.proto
+
+package blogv2
+
+import (
+	_ "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FeedEvent_Type int32
+
+const (
+	FeedEvent_TYPE_UNSPECIFIED FeedEvent_Type = 0
+	FeedEvent_HEARTBEAT        FeedEvent_Type = 1
+	FeedEvent_POST_CREATED     FeedEvent_Type = 2
+	FeedEvent_POST_UPDATED     FeedEvent_Type = 3
+	FeedEvent_POST_DELETED     FeedEvent_Type = 4
+	FeedEvent_LIKES_CHANGED    FeedEvent_Type = 5
+)
+
+// Enum value maps for FeedEvent_Type.
+var (
+	FeedEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "HEARTBEAT",
+		2: "POST_CREATED",
+		3: "POST_UPDATED",
+		4: "POST_DELETED",
+		5: "LIKES_CHANGED",
+	}
+	FeedEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"HEARTBEAT":        1,
+		"POST_CREATED":     2,
+		"POST_UPDATED":     3,
+		"POST_DELETED":     4,
+		"LIKES_CHANGED":    5,
+	}
+)
+
+func (x FeedEvent_Type) Enum() *FeedEvent_Type {
+	p := new(FeedEvent_Type)
+	*p = x
+	return p
+}
+
+func (x FeedEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FeedEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_v2_blog_proto_enumTypes[0].Descriptor()
+}
+
+func (FeedEvent_Type) Type() protoreflect.EnumType {
+	return &file_v2_blog_proto_enumTypes[0]
+}
+
+func (x FeedEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FeedEvent_Type.Descriptor instead.
+func (FeedEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{21, 0}
+}
+
+type Post struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Author     *User                  `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
+	Body       string                 `protobuf:"bytes,3,opt,name=body,proto3" json:"body,omitempty"`
+	CreatedAt  *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	LikesCount int32                  `protobuf:"varint,5,opt,name=likes_count,json=likesCount,proto3" json:"likes_count,omitempty"`
+	IsLiked    bool                   `protobuf:"varint,6,opt,name=is_liked,json=isLiked,proto3" json:"is_liked,omitempty"`
+	// Set while likes can't be read, likes_count and is_liked are then unset.
+	LikesUnknown  bool `protobuf:"varint,7,opt,name=likes_unknown,json=likesUnknown,proto3" json:"likes_unknown,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Post) Reset() {
+	*x = Post{}
+	mi := &file_v2_blog_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Post) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Post) ProtoMessage() {}
+
+func (x *Post) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Post.ProtoReflect.Descriptor instead.
+func (*Post) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Post) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Post) GetAuthor() *User {
+	if x != nil {
+		return x.Author
+	}
+	return nil
+}
+
+func (x *Post) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *Post) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Post) GetLikesCount() int32 {
+	if x != nil {
+		return x.LikesCount
+	}
+	return 0
+}
+
+func (x *Post) GetIsLiked() bool {
+	if x != nil {
+		return x.IsLiked
+	}
+	return false
+}
+
+func (x *Post) GetLikesUnknown() bool {
+	if x != nil {
+		return x.LikesUnknown
+	}
+	return false
+}
+
+type User struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	NickName      string                 `protobuf:"bytes,2,opt,name=nick_name,json=nickName,proto3" json:"nick_name,omitempty"`
+	PhotoUrl      string                 `protobuf:"bytes,3,opt,name=photo_url,json=photoUrl,proto3" json:"photo_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *User) Reset() {
+	*x = User{}
+	mi := &file_v2_blog_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *User) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*User) ProtoMessage() {}
+
+func (x *User) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use User.ProtoReflect.Descriptor instead.
+func (*User) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *User) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *User) GetNickName() string {
+	if x != nil {
+		return x.NickName
+	}
+	return ""
+}
+
+func (x *User) GetPhotoUrl() string {
+	if x != nil {
+		return x.PhotoUrl
+	}
+	return ""
+}
+
+type GetPostsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPostsRequest) Reset() {
+	*x = GetPostsRequest{}
+	mi := &file_v2_blog_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPostsRequest) ProtoMessage() {}
+
+func (x *GetPostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPostsRequest.ProtoReflect.Descriptor instead.
+func (*GetPostsRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetPostsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GetPostsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type GetPostsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Posts         []*Post                `protobuf:"bytes,1,rep,name=posts,proto3" json:"posts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPostsResponse) Reset() {
+	*x = GetPostsResponse{}
+	mi := &file_v2_blog_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPostsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPostsResponse) ProtoMessage() {}
+
+func (x *GetPostsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPostsResponse.ProtoReflect.Descriptor instead.
+func (*GetPostsResponse) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetPostsResponse) GetPosts() []*Post {
+	if x != nil {
+		return x.Posts
+	}
+	return nil
+}
+
+type CreatePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Body          string                 `protobuf:"bytes,1,opt,name=body,proto3" json:"body,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePostRequest) Reset() {
+	*x = CreatePostRequest{}
+	mi := &file_v2_blog_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePostRequest) ProtoMessage() {}
+
+func (x *CreatePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePostRequest.ProtoReflect.Descriptor instead.
+func (*CreatePostRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreatePostRequest) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+type CreatePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePostResponse) Reset() {
+	*x = CreatePostResponse{}
+	mi := &file_v2_blog_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePostResponse) ProtoMessage() {}
+
+func (x *CreatePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePostResponse.ProtoReflect.Descriptor instead.
+func (*CreatePostResponse) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreatePostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+type UpdatePostRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Body  string                 `protobuf:"bytes,2,opt,name=body,proto3" json:"body,omitempty"`
+	// Post fields to update, e.g. "body". An empty mask replaces every
+	// updatable field. Immutable fields (id, author, created_at, likes_count,
+	// is_liked) and unknown fields are rejected with INVALID_ARGUMENT.
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,3,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePostRequest) Reset() {
+	*x = UpdatePostRequest{}
+	mi := &file_v2_blog_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePostRequest) ProtoMessage() {}
+
+func (x *UpdatePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePostRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePostRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdatePostRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdatePostRequest) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *UpdatePostRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+type UpdatePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePostResponse) Reset() {
+	*x = UpdatePostResponse{}
+	mi := &file_v2_blog_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePostResponse) ProtoMessage() {}
+
+func (x *UpdatePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePostResponse.ProtoReflect.Descriptor instead.
+func (*UpdatePostResponse) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdatePostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+type DeletePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePostRequest) Reset() {
+	*x = DeletePostRequest{}
+	mi := &file_v2_blog_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePostRequest) ProtoMessage() {}
+
+func (x *DeletePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePostRequest.ProtoReflect.Descriptor instead.
+func (*DeletePostRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeletePostRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeletePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePostResponse) Reset() {
+	*x = DeletePostResponse{}
+	mi := &file_v2_blog_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePostResponse) ProtoMessage() {}
+
+func (x *DeletePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePostResponse.ProtoReflect.Descriptor instead.
+func (*DeletePostResponse) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{9}
+}
+
+type ToggleLikeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToggleLikeRequest) Reset() {
+	*x = ToggleLikeRequest{}
+	mi := &file_v2_blog_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToggleLikeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToggleLikeRequest) ProtoMessage() {}
+
+func (x *ToggleLikeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToggleLikeRequest.ProtoReflect.Descriptor instead.
+func (*ToggleLikeRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ToggleLikeRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type ToggleLikeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToggleLikeResponse) Reset() {
+	*x = ToggleLikeResponse{}
+	mi := &file_v2_blog_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToggleLikeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToggleLikeResponse) ProtoMessage() {}
+
+func (x *ToggleLikeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToggleLikeResponse.ProtoReflect.Descriptor instead.
+func (*ToggleLikeResponse) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ToggleLikeResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+// Likes the post. Liking an already liked post changes nothing, so the call
+// is safe to retry.
+type LikePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LikePostRequest) Reset() {
+	*x = LikePostRequest{}
+	mi := &file_v2_blog_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LikePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LikePostRequest) ProtoMessage() {}
+
+func (x *LikePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LikePostRequest.ProtoReflect.Descriptor instead.
+func (*LikePostRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *LikePostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type LikePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LikePostResponse) Reset() {
+	*x = LikePostResponse{}
+	mi := &file_v2_blog_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LikePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LikePostResponse) ProtoMessage() {}
+
+func (x *LikePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LikePostResponse.ProtoReflect.Descriptor instead.
+func (*LikePostResponse) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *LikePostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+// Removes the like from the post. Safe to retry as well.
+type UnlikePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlikePostRequest) Reset() {
+	*x = UnlikePostRequest{}
+	mi := &file_v2_blog_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlikePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlikePostRequest) ProtoMessage() {}
+
+func (x *UnlikePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlikePostRequest.ProtoReflect.Descriptor instead.
+func (*UnlikePostRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UnlikePostRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type UnlikePostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlikePostResponse) Reset() {
+	*x = UnlikePostResponse{}
+	mi := &file_v2_blog_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlikePostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlikePostResponse) ProtoMessage() {}
+
+func (x *UnlikePostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlikePostResponse.ProtoReflect.Descriptor instead.
+func (*UnlikePostResponse) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UnlikePostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+type ListLikersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLikersRequest) Reset() {
+	*x = ListLikersRequest{}
+	mi := &file_v2_blog_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLikersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLikersRequest) ProtoMessage() {}
+
+func (x *ListLikersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLikersRequest.ProtoReflect.Descriptor instead.
+func (*ListLikersRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListLikersRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *ListLikersRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListLikersRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// Users who liked the post, most recent like first.
+type ListLikersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Users         []*User                `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLikersResponse) Reset() {
+	*x = ListLikersResponse{}
+	mi := &file_v2_blog_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLikersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLikersResponse) ProtoMessage() {}
+
+func (x *ListLikersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLikersResponse.ProtoReflect.Descriptor instead.
+func (*ListLikersResponse) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ListLikersResponse) GetUsers() []*User {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+type ListLikedPostsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLikedPostsRequest) Reset() {
+	*x = ListLikedPostsRequest{}
+	mi := &file_v2_blog_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLikedPostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLikedPostsRequest) ProtoMessage() {}
+
+func (x *ListLikedPostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLikedPostsRequest.ProtoReflect.Descriptor instead.
+func (*ListLikedPostsRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListLikedPostsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListLikedPostsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListLikedPostsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// Posts liked by the user, most recently liked first.
+type ListLikedPostsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Posts         []*Post                `protobuf:"bytes,1,rep,name=posts,proto3" json:"posts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLikedPostsResponse) Reset() {
+	*x = ListLikedPostsResponse{}
+	mi := &file_v2_blog_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLikedPostsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLikedPostsResponse) ProtoMessage() {}
+
+func (x *ListLikedPostsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLikedPostsResponse.ProtoReflect.Descriptor instead.
+func (*ListLikedPostsResponse) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListLikedPostsResponse) GetPosts() []*Post {
+	if x != nil {
+		return x.Posts
+	}
+	return nil
+}
+
+type WatchFeedRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Id of the last event the client received. Retained events after it are
+	// sent before live ones. Fails with OUT_OF_RANGE if it is no longer retained.
+	LastEventId   string `protobuf:"bytes,1,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchFeedRequest) Reset() {
+	*x = WatchFeedRequest{}
+	mi := &file_v2_blog_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchFeedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchFeedRequest) ProtoMessage() {}
+
+func (x *WatchFeedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchFeedRequest.ProtoReflect.Descriptor instead.
+func (*WatchFeedRequest) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *WatchFeedRequest) GetLastEventId() string {
+	if x != nil {
+		return x.LastEventId
+	}
+	return ""
+}
+
+type FeedEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Empty for heartbeats.
+	Id     string         `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type   FeedEvent_Type `protobuf:"varint,2,opt,name=type,proto3,enum=blog.v2.FeedEvent_Type" json:"type,omitempty"`
+	PostId string         `protobuf:"bytes,3,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	// Set for POST_CREATED and POST_UPDATED, is_liked is always false and
+	// created_at is only precise to the second.
+	Post *Post `protobuf:"bytes,4,opt,name=post,proto3" json:"post,omitempty"`
+	// Set for LIKES_CHANGED.
+	LikesCount    int32 `protobuf:"varint,5,opt,name=likes_count,json=likesCount,proto3" json:"likes_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FeedEvent) Reset() {
+	*x = FeedEvent{}
+	mi := &file_v2_blog_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeedEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeedEvent) ProtoMessage() {}
+
+func (x *FeedEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_blog_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeedEvent.ProtoReflect.Descriptor instead.
+func (*FeedEvent) Descriptor() ([]byte, []int) {
+	return file_v2_blog_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *FeedEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *FeedEvent) GetType() FeedEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return FeedEvent_TYPE_UNSPECIFIED
+}
+
+func (x *FeedEvent) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *FeedEvent) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+func (x *FeedEvent) GetLikesCount() int32 {
+	if x != nil {
+		return x.LikesCount
+	}
+	return 0
+}
+
+var File_v2_blog_proto protoreflect.FileDescriptor
+
+const file_v2_blog_proto_rawDesc = "" +
+	"\n" +
+	"\rv2/blog.proto\x12\ablog.v2\x1a\x1cgoogle/api/annotations.proto\x1a google/protobuf/field_mask.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a.protoc-gen-openapiv2/options/annotations.proto\"\xed\x01\n" +
+	"\x04Post\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12%\n" +
+	"\x06author\x18\x02 \x01(\v2\r.blog.v2.UserR\x06author\x12\x12\n" +
+	"\x04body\x18\x03 \x01(\tR\x04body\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x1f\n" +
+	"\vlikes_count\x18\x05 \x01(\x05R\n" +
+	"likesCount\x12\x19\n" +
+	"\bis_liked\x18\x06 \x01(\bR\aisLiked\x12#\n" +
+	"\rlikes_unknown\x18\a \x01(\bR\flikesUnknown\"P\n" +
+	"\x04User\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tnick_name\x18\x02 \x01(\tR\bnickName\x12\x1b\n" +
+	"\tphoto_url\x18\x03 \x01(\tR\bphotoUrl\"?\n" +
+	"\x0fGetPostsRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x05R\x06offset\"7\n" +
+	"\x10GetPostsResponse\x12#\n" +
+	"\x05posts\x18\x01 \x03(\v2\r.blog.v2.PostR\x05posts\"'\n" +
+	"\x11CreatePostRequest\x12\x12\n" +
+	"\x04body\x18\x01 \x01(\tR\x04body\"7\n" +
+	"\x12CreatePostResponse\x12!\n" +
+	"\x04post\x18\x01 \x01(\v2\r.blog.v2.PostR\x04post\"t\n" +
+	"\x11UpdatePostRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04body\x18\x02 \x01(\tR\x04body\x12;\n" +
+	"\vupdate_mask\x18\x03 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\"7\n" +
+	"\x12UpdatePostResponse\x12!\n" +
+	"\x04post\x18\x01 \x01(\v2\r.blog.v2.PostR\x04post\"#\n" +
+	"\x11DeletePostRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x14\n" +
+	"\x12DeletePostResponse\",\n" +
+	"\x11ToggleLikeRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\"7\n" +
+	"\x12ToggleLikeResponse\x12!\n" +
+	"\x04post\x18\x01 \x01(\v2\r.blog.v2.PostR\x04post\"*\n" +
+	"\x0fLikePostRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\"5\n" +
+	"\x10LikePostResponse\x12!\n" +
+	"\x04post\x18\x01 \x01(\v2\r.blog.v2.PostR\x04post\",\n" +
+	"\x11UnlikePostRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\"7\n" +
+	"\x12UnlikePostResponse\x12!\n" +
+	"\x04post\x18\x01 \x01(\v2\r.blog.v2.PostR\x04post\"Z\n" +
+	"\x11ListLikersRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\"9\n" +
+	"\x12ListLikersResponse\x12#\n" +
+	"\x05users\x18\x01 \x03(\v2\r.blog.v2.UserR\x05users\"^\n" +
+	"\x15ListLikedPostsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\"=\n" +
+	"\x16ListLikedPostsResponse\x12#\n" +
+	"\x05posts\x18\x01 \x03(\v2\r.blog.v2.PostR\x05posts\"6\n" +
+	"\x10WatchFeedRequest\x12\"\n" +
+	"\rlast_event_id\x18\x01 \x01(\tR\vlastEventId\"\x9b\x02\n" +
+	"\tFeedEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12+\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x17.blog.v2.FeedEvent.TypeR\x04type\x12\x17\n" +
+	"\apost_id\x18\x03 \x01(\tR\x06postId\x12!\n" +
+	"\x04post\x18\x04 \x01(\v2\r.blog.v2.PostR\x04post\x12\x1f\n" +
+	"\vlikes_count\x18\x05 \x01(\x05R\n" +
+	"likesCount\"t\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\r\n" +
+	"\tHEARTBEAT\x10\x01\x12\x10\n" +
+	"\fPOST_CREATED\x10\x02\x12\x10\n" +
+	"\fPOST_UPDATED\x10\x03\x12\x10\n" +
+	"\fPOST_DELETED\x10\x04\x12\x11\n" +
+	"\rLIKES_CHANGED\x10\x052\x8e\x0e\n" +
+	"\vBlogService\x12\x9c\x01\n" +
+	"\bGetPosts\x12\x18.blog.v2.GetPostsRequest\x1a\x19.blog.v2.GetPostsResponse\"[\x92AG\n" +
+	"\x0eBlogService v2*\x16BlogServiceV2_GetPostsr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\v\x12\t/v2/posts\x12\xa7\x01\n" +
+	"\n" +
+	"CreatePost\x12\x1a.blog.v2.CreatePostRequest\x1a\x1b.blog.v2.CreatePostResponse\"`\x92AI\n" +
+	"\x0eBlogService v2*\x18BlogServiceV2_CreatePostr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v2/posts\x12\xc1\x01\n" +
+	"\n" +
+	"UpdatePost\x12\x1a.blog.v2.UpdatePostRequest\x1a\x1b.blog.v2.UpdatePostResponse\"z\x92AI\n" +
+	"\x0eBlogService v2*\x18BlogServiceV2_UpdatePostr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02(:\x01*Z\x13:\x01*2\x0e/v2/posts/{id}\x1a\x0e/v2/posts/{id}\x12\xa9\x01\n" +
+	"\n" +
+	"DeletePost\x12\x1a.blog.v2.DeletePostRequest\x1a\x1b.blog.v2.DeletePostResponse\"b\x92AI\n" +
+	"\x0eBlogService v2*\x18BlogServiceV2_DeletePostr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x10*\x0e/v2/posts/{id}\x12\xba\x01\n" +
+	"\n" +
+	"ToggleLike\x12\x1a.blog.v2.ToggleLikeRequest\x1a\x1b.blog.v2.ToggleLikeResponse\"s\x92AI\n" +
+	"\x0eBlogService v2*\x18BlogServiceV2_ToggleLiker\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02!\"\x1f/v2/posts/{post_id}/toggle_like\x12\xab\x01\n" +
+	"\bLikePost\x12\x18.blog.v2.LikePostRequest\x1a\x19.blog.v2.LikePostResponse\"j\x92AG\n" +
+	"\x0eBlogService v2*\x16BlogServiceV2_LikePostr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x1a\x1a\x18/v2/posts/{post_id}/like\x12\xb3\x01\n" +
+	"\n" +
+	"UnlikePost\x12\x1a.blog.v2.UnlikePostRequest\x1a\x1b.blog.v2.UnlikePostResponse\"l\x92AI\n" +
+	"\x0eBlogService v2*\x18BlogServiceV2_UnlikePostr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x1a*\x18/v2/posts/{post_id}/like\x12\xb5\x01\n" +
+	"\n" +
+	"ListLikers\x12\x1a.blog.v2.ListLikersRequest\x1a\x1b.blog.v2.ListLikersResponse\"n\x92AI\n" +
+	"\x0eBlogService v2*\x18BlogServiceV2_ListLikersr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x1c\x12\x1a/v2/posts/{post_id}/likers\x12\xca\x01\n" +
+	"\x0eListLikedPosts\x12\x1e.blog.v2.ListLikedPostsRequest\x1a\x1f.blog.v2.ListLikedPostsResponse\"w\x92AM\n" +
+	"\x0eBlogService v2*\x1cBlogServiceV2_ListLikedPostsr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02!\x12\x1f/v2/users/{user_id}/liked_posts\x12\x9f\x01\n" +
+	"\tWatchFeed\x12\x19.blog.v2.WatchFeedRequest\x1a\x12.blog.v2.FeedEvent\"a\x92AH\n" +
+	"\x0eBlogService v2*\x17BlogServiceV2_WatchFeedr\x1d\n" +
+	"\x1b\n" +
+	"\x15Grpc-metadata-user-id\x18\x01(\x01\x82\xd3\xe4\x93\x02\x10\x12\x0e/v2/feed/watch0\x01B\x1cZ\x1ago_grpc_blog/api/v2;blogv2b\x06proto3"
+
+var (
+	file_v2_blog_proto_rawDescOnce sync.Once
+	file_v2_blog_proto_rawDescData []byte
+)
+
+func file_v2_blog_proto_rawDescGZIP() []byte {
+	file_v2_blog_proto_rawDescOnce.Do(func() {
+		file_v2_blog_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_v2_blog_proto_rawDesc), len(file_v2_blog_proto_rawDesc)))
+	})
+	return file_v2_blog_proto_rawDescData
+}
+
+var file_v2_blog_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_v2_blog_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_v2_blog_proto_goTypes = []any{
+	(FeedEvent_Type)(0),            // 0: blog.v2.FeedEvent.Type
+	(*Post)(nil),                   // 1: blog.v2.Post
+	(*User)(nil),                   // 2: blog.v2.User
+	(*GetPostsRequest)(nil),        // 3: blog.v2.GetPostsRequest
+	(*GetPostsResponse)(nil),       // 4: blog.v2.GetPostsResponse
+	(*CreatePostRequest)(nil),      // 5: blog.v2.CreatePostRequest
+	(*CreatePostResponse)(nil),     // 6: blog.v2.CreatePostResponse
+	(*UpdatePostRequest)(nil),      // 7: blog.v2.UpdatePostRequest
+	(*UpdatePostResponse)(nil),     // 8: blog.v2.UpdatePostResponse
+	(*DeletePostRequest)(nil),      // 9: blog.v2.DeletePostRequest
+	(*DeletePostResponse)(nil),     // 10: blog.v2.DeletePostResponse
+	(*ToggleLikeRequest)(nil),      // 11: blog.v2.ToggleLikeRequest
+	(*ToggleLikeResponse)(nil),     // 12: blog.v2.ToggleLikeResponse
+	(*LikePostRequest)(nil),        // 13: blog.v2.LikePostRequest
+	(*LikePostResponse)(nil),       // 14: blog.v2.LikePostResponse
+	(*UnlikePostRequest)(nil),      // 15: blog.v2.UnlikePostRequest
+	(*UnlikePostResponse)(nil),     // 16: blog.v2.UnlikePostResponse
+	(*ListLikersRequest)(nil),      // 17: blog.v2.ListLikersRequest
+	(*ListLikersResponse)(nil),     // 18: blog.v2.ListLikersResponse
+	(*ListLikedPostsRequest)(nil),  // 19: blog.v2.ListLikedPostsRequest
+	(*ListLikedPostsResponse)(nil), // 20: blog.v2.ListLikedPostsResponse
+	(*WatchFeedRequest)(nil),       // 21: blog.v2.WatchFeedRequest
+	(*FeedEvent)(nil),              // 22: blog.v2.FeedEvent
+	(*timestamppb.Timestamp)(nil),  // 23: google.protobuf.Timestamp
+	(*fieldmaskpb.FieldMask)(nil),  // 24: google.protobuf.FieldMask
+}
+var file_v2_blog_proto_depIdxs = []int32{
+	2,  // 0: blog.v2.Post.author:type_name -> blog.v2.User
+	23, // 1: blog.v2.Post.created_at:type_name -> google.protobuf.Timestamp
+	1,  // 2: blog.v2.GetPostsResponse.posts:type_name -> blog.v2.Post
+	1,  // 3: blog.v2.CreatePostResponse.post:type_name -> blog.v2.Post
+	24, // 4: blog.v2.UpdatePostRequest.update_mask:type_name -> google.protobuf.FieldMask
+	1,  // 5: blog.v2.UpdatePostResponse.post:type_name -> blog.v2.Post
+	1,  // 6: blog.v2.ToggleLikeResponse.post:type_name -> blog.v2.Post
+	1,  // 7: blog.v2.LikePostResponse.post:type_name -> blog.v2.Post
+	1,  // 8: blog.v2.UnlikePostResponse.post:type_name -> blog.v2.Post
+	2,  // 9: blog.v2.ListLikersResponse.users:type_name -> blog.v2.User
+	1,  // 10: blog.v2.ListLikedPostsResponse.posts:type_name -> blog.v2.Post
+	0,  // 11: blog.v2.FeedEvent.type:type_name -> blog.v2.FeedEvent.Type
+	1,  // 12: blog.v2.FeedEvent.post:type_name -> blog.v2.Post
+	3,  // 13: blog.v2.BlogService.GetPosts:input_type -> blog.v2.GetPostsRequest
+	5,  // 14: blog.v2.BlogService.CreatePost:input_type -> blog.v2.CreatePostRequest
+	7,  // 15: blog.v2.BlogService.UpdatePost:input_type -> blog.v2.UpdatePostRequest
+	9,  // 16: blog.v2.BlogService.DeletePost:input_type -> blog.v2.DeletePostRequest
+	11, // 17: blog.v2.BlogService.ToggleLike:input_type -> blog.v2.ToggleLikeRequest
+	13, // 18: blog.v2.BlogService.LikePost:input_type -> blog.v2.LikePostRequest
+	15, // 19: blog.v2.BlogService.UnlikePost:input_type -> blog.v2.UnlikePostRequest
+	17, // 20: blog.v2.BlogService.ListLikers:input_type -> blog.v2.ListLikersRequest
+	19, // 21: blog.v2.BlogService.ListLikedPosts:input_type -> blog.v2.ListLikedPostsRequest
+	21, // 22: blog.v2.BlogService.WatchFeed:input_type -> blog.v2.WatchFeedRequest
+	4,  // 23: blog.v2.BlogService.GetPosts:output_type -> blog.v2.GetPostsResponse
+	6,  // 24: blog.v2.BlogService.CreatePost:output_type -> blog.v2.CreatePostResponse
+	8,  // 25: blog.v2.BlogService.UpdatePost:output_type -> blog.v2.UpdatePostResponse
+	10, // 26: blog.v2.BlogService.DeletePost:output_type -> blog.v2.DeletePostResponse
+	12, // 27: blog.v2.BlogService.ToggleLike:output_type -> blog.v2.ToggleLikeResponse
+	14, // 28: blog.v2.BlogService.LikePost:output_type -> blog.v2.LikePostResponse
+	16, // 29: blog.v2.BlogService.UnlikePost:output_type -> blog.v2.UnlikePostResponse
+	18, // 30: blog.v2.BlogService.ListLikers:output_type -> blog.v2.ListLikersResponse
+	20, // 31: blog.v2.BlogService.ListLikedPosts:output_type -> blog.v2.ListLikedPostsResponse
+	22, // 32: blog.v2.BlogService.WatchFeed:output_type -> blog.v2.FeedEvent
+	23, // [23:33] is the sub-list for method output_type
+	13, // [13:23] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_v2_blog_proto_init() }
+func file_v2_blog_proto_init() {
+	if File_v2_blog_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_v2_blog_proto_rawDesc), len(file_v2_blog_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   22,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_v2_blog_proto_goTypes,
+		DependencyIndexes: file_v2_blog_proto_depIdxs,
+		EnumInfos:         file_v2_blog_proto_enumTypes,
+		MessageInfos:      file_v2_blog_proto_msgTypes,
+	}.Build()
+	File_v2_blog_proto = out.File
+	file_v2_blog_proto_goTypes = nil
+	file_v2_blog_proto_depIdxs = nil
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// When Redis is down every command would wait for its dial or read timeout.
+// The breaker counts failing commands and after a few in a row fails the
+// following ones right away with ErrRedisUnavailable. After a cooldown one
+// command is let through to probe whether Redis is back.
+//
+// Callers treat a Redis failure as a degraded mode rather than an error
+// wherever Postgres can answer instead; see redisDown.
+
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 10 * time.Second
+)
+
+// ErrRedisUnavailable is returned instead of running a command while the
+// breaker is open.
+var ErrRedisUnavailable = errors.New("redis unavailable")
+
+// Breaker is a circuit breaker. It is closed while things work, opens after
+// Threshold consecutive failures and lets a single probe through every
+// Cooldown until one succeeds.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+	// OnChange, if set, is called when the breaker opens or closes.
+	OnChange func(open bool)
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreaker() *Breaker {
+	return &Breaker{Threshold: DefaultBreakerThreshold, Cooldown: DefaultBreakerCooldown}
+}
+
+// Allow reports whether a call may go ahead. Every allowed call must be
+// followed by Record or Release.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.Cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Record reports the outcome of an allowed call.
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	wasOpen := b.open
+	b.probing = false
+	if failed {
+		b.failures++
+		if b.open || b.failures >= b.Threshold {
+			b.open = true
+			b.openedAt = time.Now()
+		}
+	} else {
+		b.failures = 0
+		b.open = false
+	}
+	open := b.open
+	b.mu.Unlock()
+
+	if open != wasOpen && b.OnChange != nil {
+		b.OnChange(open)
+	}
+}
+
+// Trip opens the breaker, e.g. when Redis can't be reached at startup. The
+// next probe is due after Cooldown.
+func (b *Breaker) Trip() {
+	b.mu.Lock()
+	wasOpen := b.open
+	b.failures = max(b.failures, b.Threshold)
+	b.open = true
+	b.openedAt = time.Now()
+	b.mu.Unlock()
+
+	if !wasOpen && b.OnChange != nil {
+		b.OnChange(true)
+	}
+}
+
+// Release gives back an allowed call whose outcome says nothing about the
+// service, e.g. one cancelled by its caller.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// Open reports whether calls are currently being refused.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// GuardRedis routes every command of rdb through the breaker.
+func GuardRedis(rdb redis.UniversalClient, b *Breaker) {
+	rdb.AddHook(breakerHook{b})
+}
+
+type breakerHook struct {
+	breaker *Breaker
+}
+
+func (h breakerHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	if !h.breaker.Allow() {
+		return ctx, ErrRedisUnavailable
+	}
+	return ctx, nil
+}
+
+func (h breakerHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.done(ctx, cmd.Err())
+	return nil
+}
+
+func (h breakerHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	if !h.breaker.Allow() {
+		return ctx, ErrRedisUnavailable
+	}
+	return ctx, nil
+}
+
+func (h breakerHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if redisDown(cmd.Err()) {
+			err = cmd.Err()
+			break
+		}
+	}
+	h.done(ctx, err)
+	return nil
+}
+
+func (h breakerHook) done(ctx context.Context, err error) {
+	switch {
+	case errors.Is(err, ErrRedisUnavailable):
+		// Refused by the breaker itself, nothing was sent.
+	case ctx.Err() != nil:
+		h.breaker.Release()
+	default:
+		h.breaker.Record(redisFailure(err))
+	}
+}
+
+// redisFailure reports whether err means Redis couldn't be reached, as opposed
+// to a missing key or an error reply to a command that did reach it.
+func redisFailure(err error) bool {
+	if err == nil || err == redis.Nil || errors.Is(err, ErrRedisUnavailable) {
+		return false
+	}
+	var reply redis.Error
+	return !errors.As(err, &reply)
+}
+
+// redisDown reports whether err means the request has to do without Redis.
+func redisDown(err error) bool {
+	return errors.Is(err, ErrRedisUnavailable) || redisFailure(err)
+}
+
+// logDegraded logs the failure of an operation done without Redis.
+func logDegraded(what string, err error) {
+	if errors.Is(err, ErrRedisUnavailable) {
+		// The breaker already told once, don't log every request.
+		return
+	}
+	log.Printf("🔴 Redis unavailable, %s: %v", what, err)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreakerOpensAndProbes(t *testing.T) {
+	var changes []bool
+	b := &Breaker{Threshold: 2, Cooldown: 50 * time.Millisecond, OnChange: func(open bool) { changes = append(changes, open) }}
+
+	require.True(t, b.Allow())
+	b.Record(true)
+	require.True(t, b.Allow())
+	b.Record(true)
+	require.True(t, b.Open())
+	require.False(t, b.Allow())
+
+	// One probe after the cooldown, a failed one starts another cooldown.
+	time.Sleep(60 * time.Millisecond)
+	require.True(t, b.Allow())
+	require.False(t, b.Allow())
+	b.Record(true)
+	require.False(t, b.Allow())
+
+	time.Sleep(60 * time.Millisecond)
+	require.True(t, b.Allow())
+	b.Record(false)
+	require.False(t, b.Open())
+	require.True(t, b.Allow())
+
+	require.Equal(t, []bool{true, false}, changes)
+}
+
+func TestBreakerTrip(t *testing.T) {
+	var changes []bool
+	b := &Breaker{Threshold: 2, Cooldown: 50 * time.Millisecond, OnChange: func(open bool) { changes = append(changes, open) }}
+
+	b.Trip()
+	require.True(t, b.Open())
+	require.False(t, b.Allow())
+
+	time.Sleep(60 * time.Millisecond)
+	require.True(t, b.Allow())
+	b.Record(false)
+	require.False(t, b.Open())
+
+	require.Equal(t, []bool{true, false}, changes)
+}
+
+func TestServerDegradesWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	breaker := &Breaker{Threshold: 1, Cooldown: time.Hour}
+	GuardRedis(rdb, breaker)
+	mr.Close()
+
+	users := NewMemoryUserRepository()
+	posts := NewMemoryPostRepository(users)
+	require.NoError(t, users.Create(ctx, &db.User{ID: "user-1", NickName: "nick"}))
+	require.NoError(t, posts.Create(ctx, &db.Post{ID: "post-1", AuthorID: "user-1", Body: "hello", CreatedAt: time.Now()}, nil))
+	// Without Sql_DB, anything reading likes from the database would panic.
+	s := &Server{Posts: posts, Users: users, Redis_DB: rdb}
+
+	feed, err := s.getPosts(ctx, "user-2", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, feed, 1)
+	require.Equal(t, "nick", feed[0].Author.NickName)
+	require.True(t, feed[0].LikesUnknown)
+	require.True(t, breaker.Open())
+
+	_, err = s.setLike(ctx, "user-2", "post-1", LikeToggle)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
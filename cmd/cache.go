@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Postgres is the source of truth for posts and likes. The feed cache and the
+// like store only keep copies of them, so every implementation may lose or
+// refuse to hold anything and the handlers fall back to Postgres.
+
+// ErrNotCached is returned when the answer has to come from Postgres instead.
+var ErrNotCached = errors.New("not cached")
+
+// FeedCache caches the window of newest posts that GetPosts pages are sliced
+// from. The generation identifies the current state of the feed: a window
+// is stored under the generation read before loading it, and Invalidate moves
+// to a new generation so older windows are never served again.
+type FeedCache interface {
+	Generation(ctx context.Context) (int64, error)
+	// Window returns the window cached for generation, or nil if there is
+	// none. It returns ErrNotCached if the cache never holds anything.
+	Window(ctx context.Context, generation int64) ([]db.Post, error)
+	Store(ctx context.Context, generation int64, posts []db.Post) error
+	Invalidate(ctx context.Context) error
+	// Stale returns the last stored window of any generation, or nil, and
+	// the generation it was stored for, to serve while a fresh one is being
+	// rebuilt.
+	Stale(ctx context.Context) ([]db.Post, int64, error)
+	// TryLock takes the lease on rebuilding the window for up to ttl. It
+	// reports false if someone else holds it; unlock gives it back early.
+	TryLock(ctx context.Context, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// LikeCount is what the feed shows about a post's likes to one user.
+type LikeCount struct {
+	Total int64
+	Liked bool
+}
+
+// LikeMode is the change a like request asks for.
+type LikeMode string
+
+const (
+	LikeToggle LikeMode = "toggle"
+	LikeSet    LikeMode = "like"
+	LikeUnset  LikeMode = "unlike"
+)
+
+// LikeStore caches the likes of posts. A post's likes are cached all at once
+// with Fill, after which Set keeps them up to date.
+type LikeStore interface {
+	// Missing returns those of postIDs whose likes are not cached.
+	Missing(ctx context.Context, postIDs []string) ([]string, error)
+	// Fill caches the likes of a post unless they were cached meanwhile.
+	Fill(ctx context.Context, postID string, likes []db.Like) error
+	// Counts returns the likes of the cached posts among postIDs.
+	Counts(ctx context.Context, userID string, postIDs []string) (map[string]LikeCount, error)
+	// Set atomically applies mode to the user's like of a cached post and
+	// returns the resulting state, like count and whether anything changed.
+	// It returns ErrNotCached if the post's likes are not cached.
+	Set(ctx context.Context, postID, userID string, mode LikeMode) (liked bool, total int64, changed bool, err error)
+	// Likers returns the users who liked a cached post, newest like first.
+	Likers(ctx context.Context, postID string, start, stop int64) ([]string, error)
+	// Liked returns the posts a user liked, newest like first.
+	Liked(ctx context.Context, userID string, start, stop int64) ([]string, error)
+	// Drop forgets a deleted post.
+	Drop(ctx context.Context, postID string) error
+}
+
+// Cache backends selectable at startup.
+const (
+	CacheRedis  = "redis"
+	CacheMemory = "memory"
+	CacheNone   = "none"
+)
+
+// NewFeedCache returns the feed cache of the given kind. Shared caches encode
+// windows with codec.
+func NewFeedCache(kind string, rdb redis.UniversalClient, codec CacheCodec) (FeedCache, error) {
+	switch kind {
+	case CacheRedis:
+		cache := NewRedisFeedCache(rdb)
+		cache.Codec = codec
+		return cache, nil
+	case CacheMemory:
+		return NewMemoryFeedCache(), nil
+	case CacheNone:
+		return NoopFeedCache{}, nil
+	}
+	return nil, fmt.Errorf("unknown cache %q, want %s, %s or %s", kind, CacheRedis, CacheMemory, CacheNone)
+}
+
+func NewLikeStore(kind string, rdb redis.UniversalClient) (LikeStore, error) {
+	switch kind {
+	case CacheRedis:
+		return NewRedisLikeStore(rdb), nil
+	case CacheMemory:
+		return NewMemoryLikeStore(defaultMemoryLikePosts), nil
+	case CacheNone:
+		return NoopLikeStore{}, nil
+	}
+	return nil, fmt.Errorf("unknown cache %q, want %s, %s or %s", kind, CacheRedis, CacheMemory, CacheNone)
+}
+
+// feedCache returns the configured feed cache. A server built without one
+// uses Redis if it has a client and caches nothing otherwise.
+func (s *Server) feedCache() FeedCache {
+	switch {
+	case s.Cache != nil:
+		return s.Cache
+	case s.Redis_DB != nil:
+		return NewRedisFeedCache(s.Redis_DB)
+	}
+	return NoopFeedCache{}
+}
+
+// likeStore returns the configured like store, defaulting like feedCache.
+func (s *Server) likeStore() LikeStore {
+	switch {
+	case s.Likes != nil:
+		return s.Likes
+	case s.Redis_DB != nil:
+		return NewRedisLikeStore(s.Redis_DB)
+	}
+	return NoopLikeStore{}
+}
+
+// NoopFeedCache caches nothing, every page is read from Postgres.
+type NoopFeedCache struct{}
+
+func (NoopFeedCache) Generation(context.Context) (int64, error) { return 0, nil }
+
+func (NoopFeedCache) Window(context.Context, int64) ([]db.Post, error) { return nil, ErrNotCached }
+
+func (NoopFeedCache) Store(context.Context, int64, []db.Post) error { return nil }
+
+func (NoopFeedCache) Invalidate(context.Context) error { return nil }
+
+func (NoopFeedCache) Stale(context.Context) ([]db.Post, int64, error) { return nil, 0, ErrNotCached }
+
+func (NoopFeedCache) TryLock(context.Context, time.Duration) (func(), bool, error) {
+	return func() {}, true, nil
+}
+
+// NoopLikeStore caches nothing, likes are read and decided in Postgres.
+type NoopLikeStore struct{}
+
+func (NoopLikeStore) Missing(_ context.Context, postIDs []string) ([]string, error) {
+	return postIDs, nil
+}
+
+func (NoopLikeStore) Fill(context.Context, string, []db.Like) error { return nil }
+
+func (NoopLikeStore) Counts(context.Context, string, []string) (map[string]LikeCount, error) {
+	return map[string]LikeCount{}, nil
+}
+
+func (NoopLikeStore) Set(context.Context, string, string, LikeMode) (bool, int64, bool, error) {
+	return false, 0, false, ErrNotCached
+}
+
+func (NoopLikeStore) Likers(context.Context, string, int64, int64) ([]string, error) {
+	return nil, ErrNotCached
+}
+
+func (NoopLikeStore) Liked(context.Context, string, int64, int64) ([]string, error) {
+	return nil, ErrNotCached
+}
+
+func (NoopLikeStore) Drop(context.Context, string) error { return nil }
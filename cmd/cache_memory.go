@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go_grpc_blog/db"
+)
+
+// The in-process caches are private to one replica and are meant for local
+// development and tests. With several replicas each one would only see its
+// own invalidations and like changes.
+
+const defaultMemoryLikePosts = 10000
+
+// MemoryFeedCache keeps the feed window in process memory.
+type MemoryFeedCache struct {
+	mu         sync.Mutex
+	generation int64
+	windows    *lru[int64, []db.Post]
+	stale      []db.Post
+	staleGen   int64
+	rebuilding sync.Mutex
+}
+
+func NewMemoryFeedCache() *MemoryFeedCache {
+	// Only the current generation is ever read, the previous one is kept
+	// for stores that raced an invalidation.
+	return &MemoryFeedCache{windows: newLRU[int64, []db.Post](2, feedCacheTTL)}
+}
+
+func (c *MemoryFeedCache) Generation(context.Context) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation, nil
+}
+
+func (c *MemoryFeedCache) Window(_ context.Context, generation int64) ([]db.Post, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	window, _ := c.windows.Get(generation)
+	return window, nil
+}
+
+func (c *MemoryFeedCache) Store(_ context.Context, generation int64, posts []db.Post) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if posts == nil {
+		posts = []db.Post{}
+	}
+	c.windows.Add(generation, posts)
+	c.stale, c.staleGen = posts, generation
+	return nil
+}
+
+func (c *MemoryFeedCache) Invalidate(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	return nil
+}
+
+func (c *MemoryFeedCache) Stale(context.Context) ([]db.Post, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stale, c.staleGen, nil
+}
+
+// TryLock only has to keep out rebuilds of this process, the ttl is unused.
+func (c *MemoryFeedCache) TryLock(context.Context, time.Duration) (func(), bool, error) {
+	if !c.rebuilding.TryLock() {
+		return nil, false, nil
+	}
+	var once sync.Once
+	return func() { once.Do(c.rebuilding.Unlock) }, true, nil
+}
+
+// MemoryLikeStore keeps the likes of the most recently used posts in process
+// memory. It doesn't index likes by user, since evicting a post would leave
+// such an index incomplete, so Liked is always answered by Postgres.
+type MemoryLikeStore struct {
+	mu    sync.Mutex
+	posts *lru[string, map[string]time.Time]
+}
+
+// NewMemoryLikeStore returns a store holding the likes of up to maxPosts
+// posts.
+func NewMemoryLikeStore(maxPosts int) *MemoryLikeStore {
+	return &MemoryLikeStore{posts: newLRU[string, map[string]time.Time](maxPosts, 0)}
+}
+
+func (s *MemoryLikeStore) Missing(_ context.Context, postIDs []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var missing []string
+	for _, id := range postIDs {
+		if _, ok := s.posts.Get(id); !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+func (s *MemoryLikeStore) Fill(_ context.Context, postID string, likes []db.Like) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.posts.Get(postID); ok {
+		return nil
+	}
+	likers := make(map[string]time.Time, len(likes))
+	for _, like := range likes {
+		likers[like.UserID] = like.CreatedAt
+	}
+	s.posts.Add(postID, likers)
+	return nil
+}
+
+func (s *MemoryLikeStore) Counts(_ context.Context, userID string, postIDs []string) (map[string]LikeCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]LikeCount, len(postIDs))
+	for _, id := range postIDs {
+		likers, ok := s.posts.Get(id)
+		if !ok {
+			continue
+		}
+		_, liked := likers[userID]
+		counts[id] = LikeCount{Total: int64(len(likers)), Liked: liked}
+	}
+	return counts, nil
+}
+
+func (s *MemoryLikeStore) Set(_ context.Context, postID, userID string, mode LikeMode) (bool, int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	likers, ok := s.posts.Get(postID)
+	if !ok {
+		return false, 0, false, ErrNotCached
+	}
+
+	_, was := likers[userID]
+	liked := !was
+	switch mode {
+	case LikeSet:
+		liked = true
+	case LikeUnset:
+		liked = false
+	}
+
+	if liked && !was {
+		likers[userID] = time.Now()
+	} else if was && !liked {
+		delete(likers, userID)
+	}
+	return liked, int64(len(likers)), liked != was, nil
+}
+
+func (s *MemoryLikeStore) Likers(_ context.Context, postID string, start, stop int64) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	likers, ok := s.posts.Get(postID)
+	if !ok {
+		return nil, ErrNotCached
+	}
+
+	userIDs := make([]string, 0, len(likers))
+	for id := range likers {
+		userIDs = append(userIDs, id)
+	}
+	// Newest first, ties broken like ZREVRANGE does.
+	sort.Slice(userIDs, func(i, j int) bool {
+		a, b := likers[userIDs[i]], likers[userIDs[j]]
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return userIDs[i] > userIDs[j]
+	})
+
+	from, to := rangeBounds(int64(len(userIDs)), start, stop)
+	return userIDs[from:to], nil
+}
+
+func (s *MemoryLikeStore) Liked(context.Context, string, int64, int64) ([]string, error) {
+	return nil, ErrNotCached
+}
+
+func (s *MemoryLikeStore) Drop(_ context.Context, postID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.posts.Remove(postID)
+	return nil
+}
+
+// rangeBounds converts ZRANGE style start and stop indexes, which are
+// inclusive and count from the end when negative, to slice bounds.
+func rangeBounds(n, start, stop int64) (from, to int64) {
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if stop < 0 {
+		stop = n + stop
+	}
+	if start >= n || start > stop {
+		return 0, 0
+	}
+	return start, min(stop+1, n)
+}
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The near cache is an in-process tier in front of Redis that spares the hot
+// paths a Redis round trip and decoding the whole feed. It holds the current
+// generation, its window and single posts. Writes publish what they changed
+// on posts_cache:v2:invalidate and every replica drops its copy; entries also
+// expire after a short ttl, which bounds how stale a replica can get if it
+// misses a message.
+
+const (
+	nearCacheChannel = "posts_cache:" + feedCacheVersion + ":invalidate"
+	nearFeedMessage  = "feed"
+	nearPostPrefix   = "post:"
+
+	// Only the current generation's window is ever read, the previous one
+	// is kept for stores that raced an invalidation.
+	nearCacheWindows = 2
+
+	DefaultNearCacheSize = 1000
+	DefaultNearCacheTTL  = 5 * time.Second
+
+	// Posts are cached in Redis only as long as it takes to cool down, a
+	// write racing a read can leave an old copy there until it expires.
+	postCacheTTL = time.Minute
+)
+
+// cacheStats counts the hits and misses of every cache tier, e.g.
+// "feed_near_hits" or "post_redis_misses". They are served on /debug/vars by
+// CacheStatsHandler.
+var cacheStats = expvar.NewMap("cache")
+
+// CacheStatsHandler serves the cache map in the format of expvar.Handler, but
+// nothing else: the standard variables include the command line, database
+// password and all.
+func CacheStatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintf(w, "{\n\"cache\": %s\n}\n", cacheStats.String())
+	})
+}
+
+func countLookup(what, tier string, hit bool) {
+	if hit {
+		cacheStats.Add(what+"_"+tier+"_hits", 1)
+	} else {
+		cacheStats.Add(what+"_"+tier+"_misses", 1)
+	}
+}
+
+// postCacheKey tags single posts by their id rather than with the feed keys,
+// nothing reads them together, so that they spread over the whole cluster.
+func postCacheKey(id string) string {
+	return "posts_cache:" + feedCacheVersion + ":post:{" + id + "}"
+}
+
+// NearCache is a FeedCache keeping what it reads from far in process memory,
+// and a two-tier cache of single posts with Redis as the second tier.
+type NearCache struct {
+	far   FeedCache
+	rdb   redis.UniversalClient
+	ttl   time.Duration
+	Codec CacheCodec
+
+	mu sync.Mutex
+	// epoch changes on every invalidation, so that a read started before it
+	// doesn't put back what it dropped.
+	epoch             uint64
+	generation        int64
+	generationExpires time.Time
+	windows           *lru[int64, []db.Post]
+	posts             *lru[string, db.Post]
+}
+
+// NewNearCache returns a near cache in front of far holding up to size posts
+// for up to ttl. Run has to be running for it to see other replicas' writes.
+func NewNearCache(far FeedCache, rdb redis.UniversalClient, size int, ttl time.Duration) *NearCache {
+	return &NearCache{
+		far:     far,
+		rdb:     rdb,
+		ttl:     ttl,
+		windows: newLRU[int64, []db.Post](nearCacheWindows, ttl),
+		posts:   newLRU[string, db.Post](size, ttl),
+	}
+}
+
+// Run drops the entries other replicas invalidate until ctx is cancelled.
+func (c *NearCache) Run(ctx context.Context) {
+	pubsub := c.rdb.Subscribe(ctx, nearCacheChannel)
+	defer pubsub.Close()
+
+	for msg := range pubsub.ChannelWithSubscriptions(ctx, 100) {
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			// Messages published while we were disconnected are lost.
+			if msg.Kind == "subscribe" {
+				c.purge()
+			}
+		case *redis.Message:
+			c.drop(msg.Payload)
+		}
+	}
+}
+
+func (c *NearCache) drop(message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	if id, ok := strings.CutPrefix(message, nearPostPrefix); ok {
+		c.posts.Remove(id)
+		return
+	}
+	c.generationExpires = time.Time{}
+	c.windows.Purge()
+}
+
+func (c *NearCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	c.generationExpires = time.Time{}
+	c.windows.Purge()
+	c.posts.Purge()
+}
+
+// invalidate drops the entry locally and tells the other replicas to.
+func (c *NearCache) invalidate(ctx context.Context, message string) {
+	c.drop(message)
+	if err := c.rdb.Publish(ctx, nearCacheChannel, message).Err(); err != nil {
+		log.Printf("🔴 Failed to publish near cache invalidation %q: %v", message, err)
+	}
+}
+
+func (c *NearCache) Generation(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	if time.Now().Before(c.generationExpires) {
+		generation := c.generation
+		c.mu.Unlock()
+		return generation, nil
+	}
+	epoch := c.epoch
+	c.mu.Unlock()
+
+	generation, err := c.far.Generation(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	if c.epoch == epoch {
+		c.generation = generation
+		c.generationExpires = time.Now().Add(c.ttl)
+	}
+	c.mu.Unlock()
+	return generation, nil
+}
+
+func (c *NearCache) Window(ctx context.Context, generation int64) ([]db.Post, error) {
+	c.mu.Lock()
+	window, ok := c.windows.Get(generation)
+	c.mu.Unlock()
+	countLookup("feed", "near", ok)
+	if ok {
+		return window, nil
+	}
+
+	window, err := c.far.Window(ctx, generation)
+	if err != nil || window == nil {
+		return window, err
+	}
+
+	c.mu.Lock()
+	c.windows.Add(generation, window)
+	c.mu.Unlock()
+	return window, nil
+}
+
+func (c *NearCache) Store(ctx context.Context, generation int64, posts []db.Post) error {
+	if err := c.far.Store(ctx, generation, posts); err != nil {
+		return err
+	}
+	if posts == nil {
+		posts = []db.Post{}
+	}
+
+	c.mu.Lock()
+	c.windows.Add(generation, posts)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *NearCache) Invalidate(ctx context.Context) error {
+	if err := c.far.Invalidate(ctx); err != nil {
+		return err
+	}
+	c.invalidate(ctx, nearFeedMessage)
+	return nil
+}
+
+func (c *NearCache) Stale(ctx context.Context) ([]db.Post, int64, error) {
+	return c.far.Stale(ctx)
+}
+
+func (c *NearCache) TryLock(ctx context.Context, ttl time.Duration) (func(), bool, error) {
+	return c.far.TryLock(ctx, ttl)
+}
+
+// Post returns the cached post with its author, or nil if it isn't cached.
+func (c *NearCache) Post(ctx context.Context, id string) (*db.Post, error) {
+	c.mu.Lock()
+	post, ok := c.posts.Get(id)
+	epoch := c.epoch
+	c.mu.Unlock()
+	countLookup("post", "near", ok)
+	if ok {
+		return &post, nil
+	}
+
+	val, err := c.rdb.Get(ctx, postCacheKey(id)).Bytes()
+	countLookup("post", "redis", err == nil)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cached, err := decodeCachedPost(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode post: %w", err)
+	}
+
+	c.mu.Lock()
+	if c.epoch == epoch {
+		c.posts.Add(id, *cached)
+	}
+	c.mu.Unlock()
+	return cached, nil
+}
+
+// StorePost caches a post read from Postgres in both tiers.
+func (c *NearCache) StorePost(ctx context.Context, post *db.Post) error {
+	c.mu.Lock()
+	epoch := c.epoch
+	c.mu.Unlock()
+
+	payload, err := c.Codec.EncodePost(post)
+	if err != nil {
+		return fmt.Errorf("failed to encode post: %v", err)
+	}
+	if err := c.rdb.Set(ctx, postCacheKey(post.ID), payload, postCacheTTL).Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.epoch == epoch {
+		c.posts.Add(post.ID, *post)
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// ForgetPost drops a changed or deleted post from both tiers of every replica.
+func (c *NearCache) ForgetPost(ctx context.Context, id string) error {
+	err := c.rdb.Del(ctx, postCacheKey(id)).Err()
+	c.invalidate(ctx, nearPostPrefix+id)
+	return err
+}
+
+// findPost loads a post with its author, from the near cache if there is one.
+func (s *Server) findPost(ctx context.Context, id string) (*db.Post, error) {
+	if s.Near != nil {
+		post, err := s.Near.Post(ctx, id)
+		if redisDown(err) {
+			logDegraded("reading post from Postgres", err)
+		} else if err != nil {
+			log.Printf("🔴 Failed to get cached post %s: %v", id, err)
+		}
+		if post != nil {
+			return post, nil
+		}
+	}
+
+	dbPost, err := s.postRepo().Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Near != nil {
+		if err := s.Near.StorePost(ctx, dbPost); redisDown(err) {
+			logDegraded("not caching post", err)
+		} else if err != nil {
+			log.Printf("🔴 Failed to cache post %s: %v", id, err)
+		}
+	}
+	return dbPost, nil
+}
+
+// forgetPost runs after a post change has been committed.
+func (s *Server) forgetPost(ctx context.Context, id string) {
+	if s.Near == nil {
+		return
+	}
+	if err := s.Near.ForgetPost(ctx, id); err != nil {
+		log.Printf("🔴 Failed to forget cached post %s: %v", id, err)
+	}
+}
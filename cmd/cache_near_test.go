@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runNearCaches starts caches sharing rdb and waits until all of them listen
+// for invalidations.
+func runNearCaches(t *testing.T, caches ...*NearCache) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	for _, c := range caches {
+		go c.Run(ctx)
+	}
+	rdb := caches[0].rdb
+	require.Eventually(t, func() bool {
+		subs, err := rdb.PubSubNumSub(ctx, nearCacheChannel).Result()
+		return err == nil && subs[nearCacheChannel] == int64(len(caches))
+	}, time.Second, 10*time.Millisecond)
+}
+
+func cacheStat(key string) int64 {
+	v, _ := cacheStats.Get(key).(*expvar.Int)
+	if v == nil {
+		return 0
+	}
+	return v.Value()
+}
+
+func TestNearCacheDropsFeedInvalidatedByAnotherReplica(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+	a := NewNearCache(NewRedisFeedCache(rdb), rdb, 10, time.Minute)
+	b := NewNearCache(NewRedisFeedCache(rdb), rdb, 10, time.Minute)
+	runNearCaches(t, a, b)
+
+	require.NoError(t, b.Store(ctx, 0, []db.Post{{ID: "post-1"}}))
+
+	nearHits, redisHits := cacheStat("feed_near_hits"), cacheStat("feed_redis_hits")
+	generation, err := a.Generation(ctx)
+	require.NoError(t, err)
+	for range 2 {
+		window, err := a.Window(ctx, generation)
+		require.NoError(t, err)
+		require.Equal(t, []string{"post-1"}, postIDs(window))
+	}
+	// The first read went to Redis, the second one didn't.
+	require.Equal(t, redisHits+1, cacheStat("feed_redis_hits"))
+	require.Equal(t, nearHits+1, cacheStat("feed_near_hits"))
+
+	require.NoError(t, b.Invalidate(ctx))
+	require.Eventually(t, func() bool {
+		next, err := a.Generation(ctx)
+		return err == nil && next != generation
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNearCacheDropsPostForgottenByAnotherReplica(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+	a := NewNearCache(NewRedisFeedCache(rdb), rdb, 10, time.Minute)
+	b := NewNearCache(NewRedisFeedCache(rdb), rdb, 10, time.Minute)
+	runNearCaches(t, a, b)
+
+	post, err := a.Post(ctx, "post-1")
+	require.NoError(t, err)
+	require.Nil(t, post)
+
+	require.NoError(t, b.StorePost(ctx, &db.Post{ID: "post-1", Body: "first"}))
+	post, err = a.Post(ctx, "post-1")
+	require.NoError(t, err)
+	require.Equal(t, "first", post.Body)
+
+	// a now answers from memory, even if Redis changes behind its back.
+	require.NoError(t, rdb.Del(ctx, postCacheKey("post-1")).Err())
+	post, err = a.Post(ctx, "post-1")
+	require.NoError(t, err)
+	require.NotNil(t, post)
+
+	require.NoError(t, b.ForgetPost(ctx, "post-1"))
+	require.Eventually(t, func() bool {
+		post, err := a.Post(ctx, "post-1")
+		return err == nil && post == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCacheStatsHandlerOnlyServesCacheStats(t *testing.T) {
+	countLookup("feed", "near", true)
+
+	rec := httptest.NewRecorder()
+	CacheStatsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+
+	var vars map[string]map[string]int64
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vars))
+	require.Len(t, vars, 1)
+	require.Positive(t, vars["cache"]["feed_near_hits"])
+}
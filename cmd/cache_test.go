@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Every FeedCache and LikeStore runs the same contract. A backend that
+// caches nothing must still be consistent: it has to report ErrNotCached
+// instead of answering wrongly.
+
+var feedCaches = map[string]func(t *testing.T) FeedCache{
+	CacheRedis:  func(t *testing.T) FeedCache { return NewRedisFeedCache(newTestRedis(t)) },
+	CacheMemory: func(t *testing.T) FeedCache { return NewMemoryFeedCache() },
+	CacheNone:   func(t *testing.T) FeedCache { return NoopFeedCache{} },
+	"redis proto+zstd": func(t *testing.T) FeedCache {
+		cache := NewRedisFeedCache(newTestRedis(t))
+		cache.Codec = CacheCodec{Format: CacheProto, Compression: CacheZstd}
+		return cache
+	},
+	"near": func(t *testing.T) FeedCache {
+		rdb := newTestRedis(t)
+		return NewNearCache(NewRedisFeedCache(rdb), rdb, 10, time.Minute)
+	},
+}
+
+var likeStores = map[string]func(t *testing.T) LikeStore{
+	CacheRedis:  func(t *testing.T) LikeStore { return NewRedisLikeStore(newTestRedis(t)) },
+	CacheMemory: func(t *testing.T) LikeStore { return NewMemoryLikeStore(100) },
+	CacheNone:   func(t *testing.T) LikeStore { return NoopLikeStore{} },
+}
+
+func TestFeedCacheContract(t *testing.T) {
+	for name, newCache := range feedCaches {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := newCache(t)
+
+			generation, err := cache.Generation(ctx)
+			require.NoError(t, err)
+
+			window, err := cache.Window(ctx, generation)
+			if err == ErrNotCached {
+				require.NoError(t, cache.Store(ctx, generation, []db.Post{{ID: "post-1"}}))
+				_, err = cache.Window(ctx, generation)
+				require.ErrorIs(t, err, ErrNotCached)
+				_, _, err = cache.Stale(ctx)
+				require.ErrorIs(t, err, ErrNotCached)
+				require.NoError(t, cache.Invalidate(ctx))
+				return
+			}
+			require.NoError(t, err)
+			require.Nil(t, window)
+
+			stale, _, err := cache.Stale(ctx)
+			require.NoError(t, err)
+			require.Nil(t, stale)
+
+			// Only one rebuild at a time, until it is unlocked.
+			unlock, ok, err := cache.TryLock(ctx, time.Minute)
+			require.NoError(t, err)
+			require.True(t, ok)
+			_, ok, err = cache.TryLock(ctx, time.Minute)
+			require.NoError(t, err)
+			require.False(t, ok)
+			unlock()
+			unlock, ok, err = cache.TryLock(ctx, time.Minute)
+			require.NoError(t, err)
+			require.True(t, ok)
+			unlock()
+
+			require.NoError(t, cache.Store(ctx, generation, []db.Post{{ID: "post-1"}, {ID: "post-2"}}))
+			window, err = cache.Window(ctx, generation)
+			require.NoError(t, err)
+			require.Equal(t, []string{"post-1", "post-2"}, postIDs(window))
+
+			// An empty feed is cached too, not mistaken for a miss.
+			require.NoError(t, cache.Store(ctx, generation+100, nil))
+			window, err = cache.Window(ctx, generation+100)
+			require.NoError(t, err)
+			require.NotNil(t, window)
+			require.Empty(t, window)
+
+			require.NoError(t, cache.Invalidate(ctx))
+			next, err := cache.Generation(ctx)
+			require.NoError(t, err)
+			require.NotEqual(t, generation, next)
+			window, err = cache.Window(ctx, next)
+			require.NoError(t, err)
+			require.Nil(t, window)
+
+			// The last stored window outlives invalidations, along with the
+			// generation it was stored for.
+			stale, staleGeneration, err := cache.Stale(ctx)
+			require.NoError(t, err)
+			require.NotNil(t, stale)
+			require.Empty(t, stale)
+			require.Equal(t, generation+100, staleGeneration)
+		})
+	}
+}
+
+func TestLikeStoreContract(t *testing.T) {
+	for name, newStore := range likeStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+
+			missing, err := store.Missing(ctx, []string{"post-1", "post-2"})
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"post-1", "post-2"}, missing)
+
+			_, _, _, err = store.Set(ctx, "post-1", "user-1", LikeSet)
+			require.ErrorIs(t, err, ErrNotCached)
+
+			now := time.Now()
+			require.NoError(t, store.Fill(ctx, "post-1", []db.Like{
+				{PostID: "post-1", UserID: "user-1", CreatedAt: now.Add(-2 * time.Minute)},
+				{PostID: "post-1", UserID: "user-2", CreatedAt: now.Add(-time.Minute)},
+			}))
+			// A second fill never overwrites what is cached.
+			require.NoError(t, store.Fill(ctx, "post-1", nil))
+
+			missing, err = store.Missing(ctx, []string{"post-1", "post-2"})
+			require.NoError(t, err)
+			if len(missing) == 2 {
+				// Nothing is ever cached, so the rest is up to Postgres.
+				counts, err := store.Counts(ctx, "user-1", []string{"post-1"})
+				require.NoError(t, err)
+				require.Empty(t, counts)
+				_, err = store.Likers(ctx, "post-1", 0, -1)
+				require.ErrorIs(t, err, ErrNotCached)
+				_, err = store.Liked(ctx, "user-1", 0, -1)
+				require.ErrorIs(t, err, ErrNotCached)
+				require.NoError(t, store.Drop(ctx, "post-1"))
+				return
+			}
+			require.Equal(t, []string{"post-2"}, missing)
+
+			counts, err := store.Counts(ctx, "user-1", []string{"post-1", "post-2"})
+			require.NoError(t, err)
+			require.Equal(t, map[string]LikeCount{"post-1": {Total: 2, Liked: true}}, counts)
+
+			likers, err := store.Likers(ctx, "post-1", 0, -1)
+			require.NoError(t, err)
+			require.Equal(t, []string{"user-2", "user-1"}, likers)
+
+			steps := []struct {
+				user    string
+				mode    LikeMode
+				liked   bool
+				total   int64
+				changed bool
+			}{
+				{"user-3", LikeSet, true, 3, true},
+				{"user-3", LikeSet, true, 3, false},
+				{"user-1", LikeUnset, false, 2, true},
+				{"user-1", LikeUnset, false, 2, false},
+				{"user-1", LikeToggle, true, 3, true},
+				{"user-1", LikeToggle, false, 2, true},
+			}
+			for i, step := range steps {
+				liked, total, changed, err := store.Set(ctx, "post-1", step.user, step.mode)
+				require.NoError(t, err)
+				require.Equal(t, step.liked, liked, "step %d", i)
+				require.Equal(t, step.total, total, "step %d", i)
+				require.Equal(t, step.changed, changed, "step %d", i)
+			}
+
+			likers, err = store.Likers(ctx, "post-1", 0, 0)
+			require.NoError(t, err)
+			require.Equal(t, []string{"user-3"}, likers)
+
+			liked, err := store.Liked(ctx, "user-3", 0, -1)
+			if err != ErrNotCached {
+				require.NoError(t, err)
+				require.Equal(t, []string{"post-1"}, liked)
+			}
+
+			require.NoError(t, store.Drop(ctx, "post-1"))
+			missing, err = store.Missing(ctx, []string{"post-1"})
+			require.NoError(t, err)
+			require.Equal(t, []string{"post-1"}, missing)
+		})
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRU[string, int](2, 0)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	_, _ = c.Get("a")
+	c.Add("c", 3)
+
+	_, ok := c.Get("b")
+	require.False(t, ok)
+	v, ok := c.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+	require.Equal(t, 2, c.Len())
+}
+
+func postIDs(posts []db.Post) []string {
+	ids := make([]string, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	return ids
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	cachepb "go_grpc_blog/api/cache"
+	"go_grpc_blog/db"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/proto"
+)
+
+// Cached posts are encoded with a codec chosen at startup. Every payload
+// starts with a two byte header, cacheCodecMagic and then the format in the
+// high and the compression in the low nibble, so a replica can read whatever
+// any other replica wrote with any codec. Payloads without the header are
+// plain JSON as written before codecs existed.
+//
+// Replicas that predate codecs can only read JSON, they fall back to Postgres
+// on anything else. Roll out a new version with the default codec first and
+// switch codecs once every replica runs it.
+
+const cacheCodecMagic = 0xCB
+
+// maxCachePayload bounds the decompressed size of a payload.
+const maxCachePayload = 64 << 20
+
+// ErrUnknownCacheFormat is returned for a payload written by a newer codec.
+var ErrUnknownCacheFormat = errors.New("unknown cache payload format")
+
+type CacheFormat byte
+
+const (
+	CacheJSON CacheFormat = iota
+	CacheProto
+)
+
+type CacheCompression byte
+
+const (
+	CacheUncompressed CacheCompression = iota
+	CacheZstd
+	CacheSnappy
+)
+
+var cacheFormatNames = map[CacheFormat]string{
+	CacheJSON:  "json",
+	CacheProto: "proto",
+}
+
+var cacheCompressionNames = map[CacheCompression]string{
+	CacheZstd:   "zstd",
+	CacheSnappy: "snappy",
+}
+
+// CacheCodec encodes cached posts. The zero value writes uncompressed JSON,
+// which every replica can read.
+type CacheCodec struct {
+	Format      CacheFormat
+	Compression CacheCompression
+}
+
+// ParseCacheCodec parses a codec name such as "json", "proto" or
+// "proto+zstd".
+func ParseCacheCodec(name string) (CacheCodec, error) {
+	formatName, compressionName, compressed := strings.Cut(name, "+")
+
+	var codec CacheCodec
+	found := false
+	for format, n := range cacheFormatNames {
+		if n == formatName {
+			codec.Format, found = format, true
+		}
+	}
+	if !found {
+		return CacheCodec{}, fmt.Errorf("unknown cache format %q, want json or proto", formatName)
+	}
+
+	if compressed {
+		found = false
+		for compression, n := range cacheCompressionNames {
+			if n == compressionName {
+				codec.Compression, found = compression, true
+			}
+		}
+		if !found {
+			return CacheCodec{}, fmt.Errorf("unknown cache compression %q, want zstd or snappy", compressionName)
+		}
+	}
+	return codec, nil
+}
+
+func (c CacheCodec) String() string {
+	if c.Compression == CacheUncompressed {
+		return cacheFormatNames[c.Format]
+	}
+	return cacheFormatNames[c.Format] + "+" + cacheCompressionNames[c.Compression]
+}
+
+// EncodePosts encodes a feed window.
+func (c CacheCodec) EncodePosts(posts []db.Post) ([]byte, error) {
+	var body []byte
+	var err error
+	switch c.Format {
+	case CacheJSON:
+		body, err = json.Marshal(posts)
+	case CacheProto:
+		msg := &cachepb.Posts{Posts: make([]*cachepb.Post, len(posts))}
+		for i := range posts {
+			msg.Posts[i] = dbPostToCachePost(&posts[i])
+		}
+		body, err = proto.Marshal(msg)
+	default:
+		return nil, fmt.Errorf("unknown cache format %d", c.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.seal(body)
+}
+
+// EncodePost encodes a single post.
+func (c CacheCodec) EncodePost(post *db.Post) ([]byte, error) {
+	var body []byte
+	var err error
+	switch c.Format {
+	case CacheJSON:
+		body, err = json.Marshal(post)
+	case CacheProto:
+		body, err = proto.Marshal(dbPostToCachePost(post))
+	default:
+		return nil, fmt.Errorf("unknown cache format %d", c.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.seal(body)
+}
+
+// seal compresses the body and prepends the header.
+func (c CacheCodec) seal(body []byte) ([]byte, error) {
+	header := []byte{cacheCodecMagic, byte(c.Format)<<4 | byte(c.Compression)}
+	switch c.Compression {
+	case CacheUncompressed:
+		return append(header, body...), nil
+	case CacheZstd:
+		return zstdEncoder().EncodeAll(body, header), nil
+	case CacheSnappy:
+		return append(header, s2.EncodeSnappy(nil, body)...), nil
+	}
+	return nil, fmt.Errorf("unknown cache compression %d", c.Compression)
+}
+
+// open returns the format and uncompressed body of a payload.
+func openCachePayload(data []byte) (CacheFormat, []byte, error) {
+	if len(data) == 0 || data[0] != cacheCodecMagic {
+		// Written before codecs, JSON never starts with the magic byte.
+		return CacheJSON, data, nil
+	}
+	if len(data) < 2 {
+		return 0, nil, fmt.Errorf("%w: truncated header", ErrUnknownCacheFormat)
+	}
+
+	format := CacheFormat(data[1] >> 4)
+	compression := CacheCompression(data[1] & 0x0f)
+	if _, ok := cacheFormatNames[format]; !ok {
+		return 0, nil, fmt.Errorf("%w: format %d", ErrUnknownCacheFormat, format)
+	}
+
+	body := data[2:]
+	switch compression {
+	case CacheUncompressed:
+		return format, body, nil
+	case CacheZstd:
+		body, err := zstdDecoder().DecodeAll(body, nil)
+		return format, body, err
+	case CacheSnappy:
+		n, err := s2.DecodedLen(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		if n > maxCachePayload {
+			return 0, nil, fmt.Errorf("cache payload of %d bytes is too large", n)
+		}
+		body, err := s2.Decode(nil, body)
+		return format, body, err
+	}
+	return 0, nil, fmt.Errorf("%w: compression %d", ErrUnknownCacheFormat, compression)
+}
+
+// decodeCachedPosts decodes a feed window written with any codec.
+func decodeCachedPosts(data []byte) ([]db.Post, error) {
+	format, body, err := openCachePayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == CacheJSON {
+		var posts []db.Post
+		if err := json.Unmarshal(body, &posts); err != nil {
+			return nil, err
+		}
+		if posts == nil {
+			posts = []db.Post{}
+		}
+		return posts, nil
+	}
+
+	var msg cachepb.Posts
+	if err := proto.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	posts := make([]db.Post, len(msg.Posts))
+	for i, p := range msg.Posts {
+		posts[i] = cachePostToDBPost(p)
+	}
+	return posts, nil
+}
+
+// decodeCachedPost decodes a single post written with any codec.
+func decodeCachedPost(data []byte) (*db.Post, error) {
+	format, body, err := openCachePayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var post db.Post
+	if format == CacheJSON {
+		if err := json.Unmarshal(body, &post); err != nil {
+			return nil, err
+		}
+		return &post, nil
+	}
+
+	var msg cachepb.Post
+	if err := proto.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	post = cachePostToDBPost(&msg)
+	return &post, nil
+}
+
+func dbPostToCachePost(p *db.Post) *cachepb.Post {
+	msg := &cachepb.Post{
+		Id:       p.ID,
+		AuthorId: p.AuthorID,
+		Author: &cachepb.User{
+			Id:       p.Author.ID,
+			NickName: p.Author.NickName,
+			PhotoUrl: p.Author.PhotoURL,
+		},
+		Body: p.Body,
+	}
+	if !p.CreatedAt.IsZero() {
+		msg.CreatedAt = p.CreatedAt.UnixNano()
+	}
+	return msg
+}
+
+func cachePostToDBPost(msg *cachepb.Post) db.Post {
+	p := db.Post{
+		ID:       msg.Id,
+		AuthorID: msg.AuthorId,
+		Author: db.User{
+			ID:       msg.Author.GetId(),
+			NickName: msg.Author.GetNickName(),
+			PhotoURL: msg.Author.GetPhotoUrl(),
+		},
+		Body: msg.Body,
+	}
+	if msg.CreatedAt != 0 {
+		p.CreatedAt = time.Unix(0, msg.CreatedAt)
+	}
+	return p
+}
+
+// The zstd encoder and decoder are safe for concurrent EncodeAll and
+// DecodeAll calls and expensive to create, so they are shared.
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return enc
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxCachePayload))
+		return dec
+	})
+)
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+)
+
+var cacheCodecNames = []string{"json", "json+zstd", "json+snappy", "proto", "proto+zstd", "proto+snappy"}
+
+func testWindow(n int) []db.Post {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 123456789, time.Local)
+	posts := make([]db.Post, n)
+	for i := range posts {
+		author := db.User{
+			ID:       fmt.Sprintf("user-%d", i%20),
+			NickName: fmt.Sprintf("nick%d", i%20),
+			PhotoURL: fmt.Sprintf("https://example.com/photos/%d.jpg", i%20),
+		}
+		posts[i] = db.Post{
+			ID:        fmt.Sprintf("post-01JQ%022d", i),
+			AuthorID:  author.ID,
+			Author:    author,
+			Body:      fmt.Sprintf("Post number %d, with a body of a few dozen characters like most posts.", i),
+			CreatedAt: created.Add(-time.Duration(i) * time.Minute),
+		}
+	}
+	return posts
+}
+
+func TestCacheCodecsRoundTrip(t *testing.T) {
+	window := testWindow(3)
+	for _, name := range cacheCodecNames {
+		t.Run(name, func(t *testing.T) {
+			codec, err := ParseCacheCodec(name)
+			require.NoError(t, err)
+			require.Equal(t, name, codec.String())
+
+			payload, err := codec.EncodePosts(window)
+			require.NoError(t, err)
+			decoded, err := decodeCachedPosts(payload)
+			require.NoError(t, err)
+			require.Len(t, decoded, len(window))
+			for i := range window {
+				require.Equal(t, window[i].ID, decoded[i].ID)
+				require.Equal(t, window[i].Author, decoded[i].Author)
+				require.Equal(t, window[i].Body, decoded[i].Body)
+				require.True(t, window[i].CreatedAt.Equal(decoded[i].CreatedAt))
+			}
+
+			payload, err = codec.EncodePosts([]db.Post{})
+			require.NoError(t, err)
+			decoded, err = decodeCachedPosts(payload)
+			require.NoError(t, err)
+			require.NotNil(t, decoded)
+			require.Empty(t, decoded)
+
+			payload, err = codec.EncodePost(&window[0])
+			require.NoError(t, err)
+			post, err := decodeCachedPost(payload)
+			require.NoError(t, err)
+			require.Equal(t, window[0].ID, post.ID)
+		})
+	}
+
+	_, err := ParseCacheCodec("proto+lz4")
+	require.Error(t, err)
+	_, err = ParseCacheCodec("xml")
+	require.Error(t, err)
+}
+
+func TestDecodeCachedPostsReadsLegacyJSON(t *testing.T) {
+	// Replicas predating codecs stored plain JSON.
+	legacy, err := json.Marshal(testWindow(2))
+	require.NoError(t, err)
+
+	posts, err := decodeCachedPosts(legacy)
+	require.NoError(t, err)
+	require.Equal(t, []string{testWindow(2)[0].ID, testWindow(2)[1].ID}, postIDs(posts))
+}
+
+func TestDecodeCachedPostsRejectsNewerFormats(t *testing.T) {
+	_, err := decodeCachedPosts([]byte{cacheCodecMagic, 0x70, 1, 2, 3})
+	require.ErrorIs(t, err, ErrUnknownCacheFormat)
+	_, err = decodeCachedPosts([]byte{cacheCodecMagic, 0x0e, 1, 2, 3})
+	require.ErrorIs(t, err, ErrUnknownCacheFormat)
+}
+
+// go test ./cmd -run '^$' -bench CacheCodec compares the codecs on a full
+// feed window; the bytes metric is the size stored in Redis.
+func BenchmarkCacheCodecEncode(b *testing.B) {
+	window := testWindow(feedCacheWindow)
+	for _, name := range cacheCodecNames {
+		codec, _ := ParseCacheCodec(name)
+		b.Run(name, func(b *testing.B) {
+			var payload []byte
+			for i := 0; i < b.N; i++ {
+				payload, _ = codec.EncodePosts(window)
+			}
+			b.ReportMetric(float64(len(payload)), "bytes")
+		})
+	}
+}
+
+func BenchmarkCacheCodecDecode(b *testing.B) {
+	window := testWindow(feedCacheWindow)
+	for _, name := range cacheCodecNames {
+		codec, _ := ParseCacheCodec(name)
+		payload, err := codec.EncodePosts(window)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := decodeCachedPosts(payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(payload)), "bytes")
+		})
+	}
+}
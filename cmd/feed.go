@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	blog "go_grpc_blog/api"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Feed events are appended to the feed:events Redis stream, which keeps the
+// recent history for resuming clients, and published on the feed:events
+// channel so that every replica can push them to its WatchFeed streams.
+//
+// A hub without Redis only serves its own replica: it numbers events like
+// stream ids, keeps the recent ones in memory and fans them out directly.
+
+const (
+	feedEventsKey     = "feed:events"
+	feedEventsChannel = "feed:events"
+
+	// feedEventsRetained is roughly how many events can be resumed from.
+	feedEventsRetained = 10000
+
+	defaultFeedHeartbeat = 15 * time.Second
+	feedSubscriberBuffer = 64
+)
+
+// publishFeedEventScript appends an event to the stream and publishes it
+// together with its stream id in one step, so live and replayed events
+// always agree on ids and order.
+//
+//	KEYS[1] stream   ARGV[1] channel   ARGV[2] retained events   ARGV[3] event
+var publishFeedEventScript = redis.NewScript(`
+local id = redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[2], '*', 'event', ARGV[3])
+redis.call('PUBLISH', ARGV[1], id .. ' ' .. ARGV[3])
+return id
+`)
+
+// FeedHub receives feed events from Redis pub/sub and fans them out to the
+// WatchFeed streams served by this replica.
+type FeedHub struct {
+	rdb redis.UniversalClient
+	// Heartbeat is how often idle streams get a heartbeat, 0 or less means
+	// defaultFeedHeartbeat.
+	Heartbeat time.Duration
+
+	mu   sync.Mutex
+	subs map[*feedSubscription]struct{}
+	// Without Redis: the recent events, oldest first, and the last id.
+	history         []*blog.FeedEvent
+	lastMs, lastSeq uint64
+}
+
+type feedSubscription struct {
+	events  chan *blog.FeedEvent
+	dropped chan struct{}
+}
+
+func NewFeedHub(rdb redis.UniversalClient) *FeedHub {
+	return &FeedHub{
+		rdb:       rdb,
+		Heartbeat: defaultFeedHeartbeat,
+		subs:      make(map[*feedSubscription]struct{}),
+	}
+}
+
+// Run relays events published by any replica until ctx is cancelled.
+func (h *FeedHub) Run(ctx context.Context) {
+	if h.rdb == nil {
+		// Publish fans out directly.
+		return
+	}
+	pubsub := h.rdb.Subscribe(ctx, feedEventsChannel)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		id, payload, ok := strings.Cut(msg.Payload, " ")
+		if !ok {
+			continue
+		}
+		event := &blog.FeedEvent{}
+		if err := proto.Unmarshal([]byte(payload), event); err != nil {
+			log.Printf("🔴 Failed to decode feed event %s: %v", id, err)
+			continue
+		}
+		event.Id = id
+		h.broadcast(event)
+	}
+}
+
+func (h *FeedHub) broadcast(event *blog.FeedEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		select {
+		case sub.events <- event:
+		default:
+			// The stream can't keep up, drop it rather than block everyone.
+			delete(h.subs, sub)
+			close(sub.dropped)
+		}
+	}
+}
+
+func (h *FeedHub) heartbeat() time.Duration {
+	if h.Heartbeat <= 0 {
+		return defaultFeedHeartbeat
+	}
+	return h.Heartbeat
+}
+
+func (h *FeedHub) subscribe() *feedSubscription {
+	sub := &feedSubscription{
+		events:  make(chan *blog.FeedEvent, feedSubscriberBuffer),
+		dropped: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+func (h *FeedHub) unsubscribe(sub *feedSubscription) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+}
+
+// Publish sends the event to every replica's WatchFeed streams.
+func (h *FeedHub) Publish(ctx context.Context, event *blog.FeedEvent) (string, error) {
+	if h.rdb == nil {
+		return h.publishLocal(event), nil
+	}
+	payload, err := proto.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	return publishFeedEventScript.Run(ctx, h.rdb, []string{feedEventsKey}, feedEventsChannel, feedEventsRetained, payload).Text()
+}
+
+// publishLocal retains the event and sends it to this replica's streams.
+func (h *FeedHub) publishLocal(event *blog.FeedEvent) string {
+	event = proto.Clone(event).(*blog.FeedEvent)
+
+	h.mu.Lock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms > h.lastMs {
+		h.lastMs, h.lastSeq = ms, 0
+	} else {
+		h.lastSeq++
+	}
+	event.Id = fmt.Sprintf("%d-%d", h.lastMs, h.lastSeq)
+	h.history = append(h.history, event)
+	if len(h.history) > 2*feedEventsRetained {
+		h.history = slices.Clone(h.history[len(h.history)-feedEventsRetained:])
+	}
+	h.mu.Unlock()
+
+	h.broadcast(event)
+	return event.Id
+}
+
+// eventsAfter returns the retained events following lastID, oldest first.
+func (h *FeedHub) eventsAfter(ctx context.Context, lastID string) ([]*blog.FeedEvent, error) {
+	if h.rdb == nil {
+		return h.localEventsAfter(lastID)
+	}
+	first, err := h.rdb.XRangeN(ctx, feedEventsKey, "-", "+", 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(first) > 0 && streamIDLess(lastID, first[0].ID) {
+		return nil, status.Errorf(codes.OutOfRange, "event %s is no longer retained, reload the feed", lastID)
+	}
+
+	msgs, err := h.rdb.XRange(ctx, feedEventsKey, "("+lastID, "+").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*blog.FeedEvent, 0, len(msgs))
+	for _, msg := range msgs {
+		payload, _ := msg.Values["event"].(string)
+		event := &blog.FeedEvent{}
+		if err := proto.Unmarshal([]byte(payload), event); err != nil {
+			return nil, err
+		}
+		event.Id = msg.ID
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (h *FeedHub) localEventsAfter(lastID string) ([]*blog.FeedEvent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.history) > 0 && streamIDLess(lastID, h.history[0].Id) {
+		return nil, status.Errorf(codes.OutOfRange, "event %s is no longer retained, reload the feed", lastID)
+	}
+	i, _ := slices.BinarySearchFunc(h.history, lastID, func(event *blog.FeedEvent, id string) int {
+		switch {
+		case streamIDLess(event.Id, id):
+			return -1
+		case streamIDLess(id, event.Id):
+			return 1
+		}
+		return 0
+	})
+	for i < len(h.history) && !streamIDLess(lastID, h.history[i].Id) {
+		i++
+	}
+	return slices.Clone(h.history[i:]), nil
+}
+
+// streamIDLess compares Redis stream ids of the form "<ms>-<seq>".
+func streamIDLess(a, b string) bool {
+	aMs, aSeq, _ := parseStreamID(a)
+	bMs, bSeq, _ := parseStreamID(b)
+	if aMs != bMs {
+		return aMs < bMs
+	}
+	return aSeq < bSeq
+}
+
+func parseStreamID(id string) (ms, seq uint64, ok bool) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	ms, msErr := strconv.ParseUint(msPart, 10, 64)
+	seq, seqErr := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq, found && msErr == nil && seqErr == nil
+}
+
+func (s *Server) WatchFeed(req *blog.WatchFeedRequest, stream blog.BlogService_WatchFeedServer) error {
+	return s.watchFeed(stream.Context(), req.LastEventId, stream.Send)
+}
+
+// watchFeed sends feed events to a WatchFeed stream until the client goes
+// away, starting after lastID if it is set.
+func (s *Server) watchFeed(ctx context.Context, lastID string, send func(*blog.FeedEvent) error) error {
+	if _, err := userIDFromContext(ctx); err != nil {
+		return err
+	}
+	if s.Feed == nil {
+		return status.Error(codes.Unavailable, "feed events are not enabled")
+	}
+
+	// Subscribe before replaying so nothing published in between is missed.
+	sub := s.Feed.subscribe()
+	defer s.Feed.unsubscribe(sub)
+
+	if lastID != "" {
+		if _, _, ok := parseStreamID(lastID); !ok {
+			return status.Errorf(codes.InvalidArgument, "invalid last_event_id %q", lastID)
+		}
+
+		missed, err := s.Feed.eventsAfter(ctx, lastID)
+		if err != nil {
+			if _, ok := status.FromError(err); ok {
+				return err
+			}
+			return status.Errorf(codes.Internal, "failed to replay feed events: %v", err)
+		}
+		for _, event := range missed {
+			if err := send(event); err != nil {
+				return err
+			}
+			lastID = event.Id
+		}
+	}
+
+	heartbeat := time.NewTicker(s.Feed.heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.dropped:
+			return status.Error(codes.ResourceExhausted, "stream fell too far behind, reconnect with last_event_id")
+		case <-heartbeat.C:
+			if err := send(&blog.FeedEvent{Type: blog.FeedEvent_HEARTBEAT}); err != nil {
+				return err
+			}
+		case event := <-sub.events:
+			// Events already sent during the replay arrive again live.
+			if lastID != "" && !streamIDLess(lastID, event.Id) {
+				continue
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+			lastID = event.Id
+			heartbeat.Reset(s.Feed.heartbeat())
+		}
+	}
+}
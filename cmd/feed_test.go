@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	blog "go_grpc_blog/api"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFeedHubRelaysPublishedEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := NewFeedHub(newTestRedis(t))
+	go hub.Run(ctx)
+	sub := hub.subscribe()
+	defer hub.unsubscribe(sub)
+
+	// Run subscribes asynchronously, keep publishing until it is listening.
+	var received *blog.FeedEvent
+	require.Eventually(t, func() bool {
+		if _, err := hub.Publish(ctx, &blog.FeedEvent{Type: blog.FeedEvent_POST_DELETED, PostId: "post-1"}); err != nil {
+			return false
+		}
+		select {
+		case received = <-sub.events:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, 20*time.Millisecond)
+
+	require.Equal(t, blog.FeedEvent_POST_DELETED, received.Type)
+	require.Equal(t, "post-1", received.PostId)
+	_, _, ok := parseStreamID(received.Id)
+	require.True(t, ok, "event id %q", received.Id)
+}
+
+func TestFeedHubReplaysEventsAfterLastID(t *testing.T) {
+	ctx := context.Background()
+	hub := NewFeedHub(newTestRedis(t))
+
+	var ids []string
+	for _, postID := range []string{"post-1", "post-2", "post-3"} {
+		id, err := hub.Publish(ctx, &blog.FeedEvent{Type: blog.FeedEvent_POST_CREATED, PostId: postID})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	events, err := hub.eventsAfter(ctx, ids[0])
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, ids[1], events[0].Id)
+	require.Equal(t, "post-3", events[1].PostId)
+
+	_, err = hub.eventsAfter(ctx, "1-0")
+	require.Equal(t, codes.OutOfRange, status.Code(err))
+}
+
+func TestFeedHubWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	hub := NewFeedHub(nil)
+	hub.Run(ctx)
+	sub := hub.subscribe()
+	defer hub.unsubscribe(sub)
+
+	var ids []string
+	for _, postID := range []string{"post-1", "post-2", "post-3"} {
+		id, err := hub.Publish(ctx, &blog.FeedEvent{Type: blog.FeedEvent_POST_CREATED, PostId: postID})
+		require.NoError(t, err)
+		ids = append(ids, id)
+
+		received := <-sub.events
+		require.Equal(t, id, received.Id)
+		require.Equal(t, postID, received.PostId)
+	}
+	require.True(t, streamIDLess(ids[0], ids[1]))
+	require.True(t, streamIDLess(ids[1], ids[2]))
+
+	events, err := hub.eventsAfter(ctx, ids[0])
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, ids[1], events[0].Id)
+	require.Equal(t, "post-3", events[1].PostId)
+
+	_, err = hub.eventsAfter(ctx, "1-0")
+	require.Equal(t, codes.OutOfRange, status.Code(err))
+}
+
+func TestFeedHubDropsSlowSubscribers(t *testing.T) {
+	hub := NewFeedHub(nil)
+	slow := hub.subscribe()
+
+	for i := 0; i <= feedSubscriberBuffer; i++ {
+		hub.broadcast(&blog.FeedEvent{Type: blog.FeedEvent_LIKES_CHANGED})
+	}
+
+	select {
+	case <-slow.dropped:
+	default:
+		t.Fatal("slow subscriber was not dropped")
+	}
+	require.Empty(t, hub.subs)
+}
+
+func TestFeedHubHeartbeatDefaultsWhenNotPositive(t *testing.T) {
+	hub := NewFeedHub(nil)
+	for _, heartbeat := range []time.Duration{0, -time.Second} {
+		hub.Heartbeat = heartbeat
+		require.Equal(t, defaultFeedHeartbeat, hub.heartbeat())
+	}
+	hub.Heartbeat = time.Second
+	require.Equal(t, time.Second, hub.heartbeat())
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gorm.io/gorm"
+)
+
+// The server can't work without Postgres, but it keeps serving without Redis
+// in a degraded mode: feeds come from Postgres with likes marked unknown and
+// like writes are refused with Unavailable. Health reports which it is, on the
+// gRPC health service and on /healthz. A server built without Redis, i.e.
+// with a nil client, doesn't report it at all.
+
+// RedisHealthService is the gRPC health service name reporting Redis. The
+// overall status ("") only depends on Postgres.
+const RedisHealthService = "redis"
+
+const defaultHealthInterval = 2 * time.Second
+
+// Health checks the backends every Interval.
+type Health struct {
+	sqlDB    *gorm.DB
+	rdb      redis.UniversalClient
+	breaker  *Breaker
+	Interval time.Duration
+	// GRPC serves the statuses on the gRPC health service.
+	GRPC *health.Server
+
+	mu       sync.Mutex
+	postgres bool
+	redis    bool
+}
+
+func NewHealth(sqlDB *gorm.DB, rdb redis.UniversalClient, breaker *Breaker) *Health {
+	return &Health{
+		sqlDB:    sqlDB,
+		rdb:      rdb,
+		breaker:  breaker,
+		Interval: defaultHealthInterval,
+		GRPC:     health.NewServer(),
+		postgres: true,
+		redis:    true,
+	}
+}
+
+// Run keeps the statuses up to date until ctx is cancelled.
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+
+	for {
+		h.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Health) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, h.Interval)
+	defer cancel()
+
+	postgres := false
+	if sqlDB, err := h.sqlDB.DB(); err == nil {
+		postgres = sqlDB.PingContext(ctx) == nil
+	}
+
+	// While the breaker is open the ping is refused until it is due to
+	// probe, and then it is the probe that closes it again.
+	redisUp := true
+	if h.rdb != nil {
+		redisUp = h.rdb.Ping(ctx).Err() == nil && (h.breaker == nil || !h.breaker.Open())
+	}
+
+	h.mu.Lock()
+	h.postgres, h.redis = postgres, redisUp
+	h.mu.Unlock()
+
+	h.GRPC.SetServingStatus("", servingStatus(postgres))
+	if h.rdb != nil {
+		h.GRPC.SetServingStatus(RedisHealthService, servingStatus(redisUp))
+	}
+}
+
+func servingStatus(up bool) healthpb.HealthCheckResponse_ServingStatus {
+	if up {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+type healthResponse struct {
+	Status   string `json:"status"`
+	Postgres string `json:"postgres"`
+	Redis    string `json:"redis"`
+}
+
+// ServeHTTP answers /healthz with 200 while the server can serve, degraded or
+// not, and 503 without Postgres.
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	postgres, redisUp := h.postgres, h.redis
+	h.mu.Unlock()
+
+	resp := healthResponse{Status: "ok", Postgres: "up", Redis: "up"}
+	code := http.StatusOK
+	if h.rdb == nil {
+		resp.Redis = "disabled"
+	} else if !redisUp {
+		resp.Status, resp.Redis = "degraded", "down"
+	}
+	if !postgres {
+		resp.Status, resp.Postgres = "down", "down"
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,527 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Likes are stored in Redis as:
+//
+//	post:{<id>}:likes  hash    "total-likes" counter plus one field per user who liked the post
+//	post:{<id>}:likers zset    user ids scored by the time of the like (unix ms)
+//	user:{<id>}:liked  zset    post ids scored by the time of the like (unix ms)
+//
+// The hash answers "how many" and "did I like it" for the feed, the sorted
+// sets answer "who liked it" and "what did they like", newest first.
+//
+// A post's keys share a hash slot and are changed atomically. The user's
+// index lives in another slot and is updated right after, so after a crash
+// in between it can miss a like until the cache is rebuilt.
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+
+	likeIndexesMigrationKey = "migrations:like-indexes"
+	likeKeysMigrationKey    = "migrations:hash-tagged-like-keys"
+)
+
+func postLikesKey(postID string) string {
+	return "post:{" + postID + "}:likes"
+}
+
+func postLikersKey(postID string) string {
+	return "post:{" + postID + "}:likers"
+}
+
+func userLikedKey(userID string) string {
+	return "user:{" + userID + "}:liked"
+}
+
+// postLikesPattern matches every postLikesKey, postIDFromLikesKey reverses it.
+const postLikesPattern = "post:{*}:likes"
+
+func postIDFromLikesKey(key string) string {
+	return key[len("post:{") : len(key)-len("}:likes")]
+}
+
+func listWindow(limit, offset int32) (start, stop int64) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return int64(offset), int64(offset) + int64(limit) - 1
+}
+
+// RedisLikeStore caches likes in Redis, shared by every replica.
+type RedisLikeStore struct {
+	rdb redis.UniversalClient
+}
+
+func NewRedisLikeStore(rdb redis.UniversalClient) *RedisLikeStore {
+	return &RedisLikeStore{rdb: rdb}
+}
+
+// Missing treats a post as cached once its hash has a total-likes field.
+func (r *RedisLikeStore) Missing(ctx context.Context, postIDs []string) ([]string, error) {
+	pipe := r.rdb.Pipeline()
+	existsCmds := make([]*redis.BoolCmd, len(postIDs))
+	for i, id := range postIDs {
+		existsCmds[i] = pipe.HExists(ctx, postLikesKey(id), "total-likes")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for i, id := range postIDs {
+		if !existsCmds[i].Val() {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// Fill writes a post's likes to Redis unless another request cached them
+// first, in which case the existing hash wins.
+func (r *RedisLikeStore) Fill(ctx context.Context, postID string, likes []db.Like) error {
+	likesKey := postLikesKey(postID)
+
+	err := r.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		exists, err := tx.HExists(ctx, likesKey, "total-likes").Result()
+		if err != nil || exists {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			fields := []interface{}{"total-likes", len(likes)}
+			for _, like := range likes {
+				fields = append(fields, like.UserID, true)
+				score := float64(like.CreatedAt.UnixMilli())
+				pipe.ZAdd(ctx, postLikersKey(postID), &redis.Z{Score: score, Member: like.UserID})
+			}
+			pipe.HSet(ctx, likesKey, fields...)
+			return nil
+		})
+		return err
+	}, likesKey)
+
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil
+	}
+	if err != nil || len(likes) == 0 {
+		return err
+	}
+
+	// The users' indexes are in other slots, outside the transaction.
+	pipe := r.rdb.Pipeline()
+	for _, like := range likes {
+		score := float64(like.CreatedAt.UnixMilli())
+		pipe.ZAdd(ctx, userLikedKey(like.UserID), &redis.Z{Score: score, Member: postID})
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisLikeStore) Counts(ctx context.Context, userID string, postIDs []string) (map[string]LikeCount, error) {
+	pipe := r.rdb.Pipeline()
+	likeCmds := make([]*redis.StringCmd, len(postIDs))
+	userLikeCmds := make([]*redis.StringCmd, len(postIDs))
+
+	for i, id := range postIDs {
+		likeCmds[i] = pipe.HGet(ctx, postLikesKey(id), "total-likes")
+		userLikeCmds[i] = pipe.HGet(ctx, postLikesKey(id), userID)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	counts := make(map[string]LikeCount, len(postIDs))
+	for i, id := range postIDs {
+		totalLikes, err := likeCmds[i].Int64()
+		if err != nil {
+			continue
+		}
+
+		isLikedStr, err := userLikeCmds[i].Result()
+		counts[id] = LikeCount{
+			Total: totalLikes,
+			Liked: err == nil && isLikedStr == "1",
+		}
+	}
+	return counts, nil
+}
+
+// setLikeScript likes, unlikes or toggles a post and keeps the hash, the
+// likers and the total-likes counter consistent in a single round trip.
+// total-likes is recomputed from the number of user fields, so it can never
+// drift or go negative.
+//
+//	KEYS[1] post:{<id>}:likes   KEYS[2] post:{<id>}:likers
+//	ARGV[1] user id             ARGV[2] like time (unix ms)
+//	ARGV[3] "toggle", "like" or "unlike"
+//
+// Returns {liked, total-likes, changed}, or nil if the post's likes are not
+// cached.
+var setLikeScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], 'total-likes') == 0 then
+	return false
+end
+
+local was = redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1
+local liked = not was
+if ARGV[3] == 'like' then
+	liked = true
+elseif ARGV[3] == 'unlike' then
+	liked = false
+end
+
+if liked and not was then
+	redis.call('HSET', KEYS[1], ARGV[1], 1)
+	redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+elseif was and not liked then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+	redis.call('ZREM', KEYS[2], ARGV[1])
+end
+
+local total = redis.call('HLEN', KEYS[1]) - 1
+redis.call('HSET', KEYS[1], 'total-likes', total)
+
+return {liked and 1 or 0, total, liked ~= was and 1 or 0}
+`)
+
+func (r *RedisLikeStore) Set(ctx context.Context, postID, userID string, mode LikeMode) (liked bool, total int64, changed bool, err error) {
+	now := time.Now().UnixMilli()
+	keys := []string{postLikesKey(postID), postLikersKey(postID)}
+	res, err := setLikeScript.Run(ctx, r.rdb, keys, userID, now, string(mode)).Int64Slice()
+	if err == redis.Nil {
+		return false, 0, false, ErrNotCached
+	}
+	if err != nil {
+		return false, 0, false, err
+	}
+	liked, total, changed = res[0] == 1, res[1], res[2] == 1
+
+	if changed {
+		if liked {
+			err = r.rdb.ZAdd(ctx, userLikedKey(userID), &redis.Z{Score: float64(now), Member: postID}).Err()
+		} else {
+			err = r.rdb.ZRem(ctx, userLikedKey(userID), postID).Err()
+		}
+		if err != nil {
+			log.Printf("🔴 Failed to update liked posts of %s: %v", userID, err)
+		}
+	}
+	return liked, total, changed, nil
+}
+
+func (r *RedisLikeStore) Likers(ctx context.Context, postID string, start, stop int64) ([]string, error) {
+	return r.rdb.ZRevRange(ctx, postLikersKey(postID), start, stop).Result()
+}
+
+func (r *RedisLikeStore) Liked(ctx context.Context, userID string, start, stop int64) ([]string, error) {
+	return r.rdb.ZRevRange(ctx, userLikedKey(userID), start, stop).Result()
+}
+
+// Drop removes every trace of a deleted post's likes.
+func (r *RedisLikeStore) Drop(ctx context.Context, postID string) error {
+	likers, err := r.rdb.ZRange(ctx, postLikersKey(postID), 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	pipe := r.rdb.Pipeline()
+	for _, userID := range likers {
+		pipe.ZRem(ctx, userLikedKey(userID), postID)
+	}
+	pipe.Del(ctx, postLikesKey(postID), postLikersKey(postID))
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// attachLikes loads like counts and the user's own like for each post.
+func (s *Server) attachLikes(ctx context.Context, userID string, dbPosts []db.Post) ([]likedPost, error) {
+	postIDs := make([]string, len(dbPosts))
+	for i, p := range dbPosts {
+		postIDs[i] = p.ID
+	}
+
+	counts, err := s.likeStore().Counts(ctx, userID, postIDs)
+	if redisDown(err) {
+		logDegraded("serving posts without likes", err)
+		return withoutLikes(dbPosts), nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch likes: %v", err)
+	}
+
+	posts := make([]likedPost, len(dbPosts))
+	var missing []string
+	for i, p := range dbPosts {
+		count, ok := counts[p.ID]
+		if !ok {
+			missing = append(missing, p.ID)
+		}
+		posts[i] = likedPost{
+			Post:       p,
+			LikesCount: int32(count.Total),
+			IsLiked:    count.Liked,
+		}
+	}
+
+	if len(missing) == 0 {
+		return posts, nil
+	}
+
+	// Likes of these posts are not cached yet, take them from Postgres.
+	likesByPost, err := s.warmLikes(ctx, missing)
+	if redisDown(err) {
+		logDegraded("serving posts without likes", err)
+		return withoutLikes(dbPosts), nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load likes: %v", err)
+	}
+	for i := range posts {
+		likes, ok := likesByPost[posts[i].ID]
+		if !ok {
+			continue
+		}
+		posts[i].LikesCount = int32(len(likes))
+		posts[i].IsLiked = false
+		for _, like := range likes {
+			if like.UserID == userID {
+				posts[i].IsLiked = true
+			}
+		}
+	}
+
+	return posts, nil
+}
+
+// withoutLikes returns the posts with their likes marked unknown.
+func withoutLikes(dbPosts []db.Post) []likedPost {
+	posts := make([]likedPost, len(dbPosts))
+	for i, p := range dbPosts {
+		posts[i] = likedPost{Post: p, LikesUnknown: true}
+	}
+	return posts
+}
+
+func (s *Server) listLikers(ctx context.Context, postID string, limit, offset int32) ([]db.User, error) {
+	exists, err := s.postRepo().Exists(ctx, postID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch post: %v", err)
+	}
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "post not found: %s", postID)
+	}
+
+	if _, err := s.warmLikes(ctx, []string{postID}); err != nil && !redisDown(err) {
+		return nil, status.Errorf(codes.Internal, "failed to load likes: %v", err)
+	}
+
+	start, stop := listWindow(limit, offset)
+	userIDs, err := s.likeStore().Likers(ctx, postID, start, stop)
+	if errors.Is(err, ErrNotCached) || redisDown(err) {
+		userIDs, err = s.likeRepo().Likers(ctx, postID, int(start), int(stop-start+1))
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch likers: %v", err)
+	}
+	if len(userIDs) == 0 {
+		return []db.User{}, nil
+	}
+
+	dbUsers, err := s.userRepo().GetMany(ctx, userIDs)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch users: %v", err)
+	}
+
+	byID := make(map[string]db.User, len(dbUsers))
+	for _, u := range dbUsers {
+		byID[u.ID] = u
+	}
+	users := make([]db.User, 0, len(userIDs))
+	for _, id := range userIDs {
+		if u, ok := byID[id]; ok {
+			users = append(users, u)
+		}
+	}
+
+	return users, nil
+}
+
+func (s *Server) listLikedPosts(ctx context.Context, currentUserID, userID string, limit, offset int32) ([]likedPost, error) {
+	start, stop := listWindow(limit, offset)
+	postIDs, err := s.likeStore().Liked(ctx, userID, start, stop)
+	if errors.Is(err, ErrNotCached) || redisDown(err) {
+		postIDs, err = s.likeRepo().Liked(ctx, userID, int(start), int(stop-start+1))
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch liked posts: %v", err)
+	}
+	if len(postIDs) == 0 {
+		return []likedPost{}, nil
+	}
+
+	dbPosts, err := s.postRepo().GetMany(ctx, postIDs)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch posts: %v", err)
+	}
+
+	byID := make(map[string]db.Post, len(dbPosts))
+	for _, p := range dbPosts {
+		byID[p.ID] = p
+	}
+	ordered := make([]db.Post, 0, len(postIDs))
+	for _, id := range postIDs {
+		if p, ok := byID[id]; ok {
+			ordered = append(ordered, p)
+		}
+	}
+
+	return s.attachLikes(ctx, currentUserID, ordered)
+}
+
+// MigrateLikeKeys renames like keys written before they were hash-tagged,
+// e.g. post:<id>:likes to post:{<id>}:likes. Such keys only exist in single
+// node and Sentinel deployments, where renaming across slots is fine. A key
+// that already exists under the new name is newer and wins.
+//
+// Replicas starting together may migrate concurrently; a key another one
+// renamed first is skipped. Replicas from before the hash tags keep writing
+// the old names, which nothing reads once the migration is marked done, so
+// they have to be drained before the first new replica starts.
+func MigrateLikeKeys(ctx context.Context, rdb redis.UniversalClient) error {
+	done, err := rdb.Exists(ctx, likeKeysMigrationKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check like keys migration: %v", err)
+	}
+	if done > 0 {
+		return nil
+	}
+
+	renames := []struct {
+		pattern, prefix, suffix string
+		key                     func(id string) string
+	}{
+		{"post:*:likes", "post:", ":likes", postLikesKey},
+		{"post:*:likers", "post:", ":likers", postLikersKey},
+		{"user:*:liked", "user:", ":liked", userLikedKey},
+	}
+
+	migrated := 0
+	for _, rename := range renames {
+		// Collect first, renaming while scanning may visit keys twice.
+		var oldKeys []string
+		err := scanKeys(ctx, rdb, rename.pattern, func(key string) error {
+			if !strings.Contains(key, "{") {
+				oldKeys = append(oldKeys, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scan like keys: %v", err)
+		}
+
+		for _, oldKey := range oldKeys {
+			id := strings.TrimSuffix(strings.TrimPrefix(oldKey, rename.prefix), rename.suffix)
+			renamed, err := rdb.RenameNX(ctx, oldKey, rename.key(id)).Result()
+			if isNoSuchKey(err) {
+				// Another replica migrated it meanwhile.
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to rename %s: %v", oldKey, err)
+			}
+			if !renamed {
+				if err := rdb.Del(ctx, oldKey).Err(); err != nil {
+					return fmt.Errorf("failed to delete %s: %v", oldKey, err)
+				}
+			}
+			migrated++
+		}
+	}
+
+	if err := rdb.Set(ctx, likeKeysMigrationKey, time.Now().Unix(), 0).Err(); err != nil {
+		return fmt.Errorf("failed to mark like keys migration: %v", err)
+	}
+	log.Printf("🟢 Migrated %d like keys to hash-tagged names", migrated)
+
+	return nil
+}
+
+// isNoSuchKey reports whether err is Redis refusing to rename a missing key.
+func isNoSuchKey(err error) bool {
+	var reply redis.Error
+	return errors.As(err, &reply) && strings.Contains(reply.Error(), "no such key")
+}
+
+// MigrateLikeIndexes builds the likers and liked sorted sets from the
+// post:{<id>}:likes hashes written before they existed. The original like times
+// are unknown, so migrated likes get score 0 and sort after every new like.
+// It is safe to run concurrently and more than once.
+func MigrateLikeIndexes(ctx context.Context, rdb redis.UniversalClient) error {
+	done, err := rdb.Exists(ctx, likeIndexesMigrationKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check like index migration: %v", err)
+	}
+	if done > 0 {
+		return nil
+	}
+
+	migrated := 0
+	err = scanKeys(ctx, rdb, postLikesPattern, func(key string) error {
+		postID := postIDFromLikesKey(key)
+
+		fields, err := rdb.HGetAll(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", key, err)
+		}
+
+		pipe := rdb.Pipeline()
+		for userID, value := range fields {
+			if userID == "total-likes" {
+				continue
+			}
+			if liked, _ := strconv.ParseBool(value); !liked {
+				continue
+			}
+			pipe.ZAddNX(ctx, postLikersKey(postID), &redis.Z{Score: 0, Member: userID})
+			pipe.ZAddNX(ctx, userLikedKey(userID), &redis.Z{Score: 0, Member: postID})
+			migrated++
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to index likes of %s: %v", key, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := rdb.Set(ctx, likeIndexesMigrationKey, time.Now().Unix(), 0).Err(); err != nil {
+		return fmt.Errorf("failed to mark like index migration: %v", err)
+	}
+	log.Printf("🟢 Migrated %d likes to the likers indexes", migrated)
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Postgres owns likes, Redis only caches them. Every like change is written
+// to the likes table, and any post whose hash is missing from Redis is
+// re-cached from Postgres on first use.
+
+const likesSyncBatchSize = 1000
+
+// warmLikes caches the likes of posts the like store doesn't hold yet. It
+// returns the likes loaded from Postgres for those posts.
+func (s *Server) warmLikes(ctx context.Context, postIDs []string) (map[string][]db.Like, error) {
+	store := s.likeStore()
+	missing, err := store.Missing(ctx, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	likes, err := s.likeRepo().ForPosts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	likesByPost := make(map[string][]db.Like, len(missing))
+	for _, id := range missing {
+		likesByPost[id] = nil
+	}
+	for _, like := range likes {
+		likesByPost[like.PostID] = append(likesByPost[like.PostID], like)
+	}
+
+	for postID, postLikes := range likesByPost {
+		if err := store.Fill(ctx, postID, postLikes); err != nil {
+			return nil, err
+		}
+	}
+
+	return likesByPost, nil
+}
+
+// SyncLikes reconciles likes between Postgres and Redis at startup:
+//   - if Redis holds likes that were never persisted, they are imported into Postgres;
+//   - if Redis lost its likes, they are rebuilt from Postgres.
+//
+// Other like stores start empty and are filled on first use.
+func SyncLikes(s *Server, ctx context.Context) error {
+	store, ok := s.likeStore().(*RedisLikeStore)
+	if !ok {
+		return nil
+	}
+
+	persisted, err := s.likeRepo().Count(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count likes: %v", err)
+	}
+
+	cached := false
+	err = scanKeys(ctx, store.rdb, postLikesPattern, func(string) error {
+		cached = true
+		return errStopScan
+	})
+	if err != nil && !errors.Is(err, errStopScan) {
+		return fmt.Errorf("failed to scan likes: %v", err)
+	}
+
+	switch {
+	case persisted == 0 && cached:
+		return importLikes(s, store.rdb, ctx)
+	case persisted > 0 && !cached:
+		return rebuildLikesCache(s, store.rdb, ctx)
+	}
+	return nil
+}
+
+// PrepareLikes migrates the Redis like keys and reconciles them with Postgres,
+// see MigrateLikeKeys, MigrateLikeIndexes and SyncLikes. It runs at startup,
+// or once Redis is back if it was down then.
+func PrepareLikes(s *Server, ctx context.Context) error {
+	store, ok := s.likeStore().(*RedisLikeStore)
+	if !ok {
+		return nil
+	}
+	if err := MigrateLikeKeys(ctx, store.rdb); err != nil {
+		return fmt.Errorf("failed to migrate like keys: %v", err)
+	}
+	if err := MigrateLikeIndexes(ctx, store.rdb); err != nil {
+		return fmt.Errorf("failed to migrate likes: %v", err)
+	}
+	if err := SyncLikes(s, ctx); err != nil {
+		return fmt.Errorf("failed to sync likes: %v", err)
+	}
+	return nil
+}
+
+// importLikes copies likes that only exist in Redis into Postgres.
+func importLikes(s *Server, rdb redis.UniversalClient, ctx context.Context) error {
+	var imported int64
+	err := scanKeys(ctx, rdb, postLikesPattern, func(key string) error {
+		postID := postIDFromLikesKey(key)
+
+		exists, err := s.postRepo().Exists(ctx, postID)
+		if err != nil {
+			return fmt.Errorf("failed to check post %s: %v", postID, err)
+		}
+		if !exists {
+			return nil
+		}
+
+		fields, err := rdb.HGetAll(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", key, err)
+		}
+
+		var likes []db.Like
+		for userID, value := range fields {
+			if userID == "total-likes" {
+				continue
+			}
+			if liked, _ := strconv.ParseBool(value); !liked {
+				continue
+			}
+
+			createdAt := time.Now()
+			if score, err := rdb.ZScore(ctx, postLikersKey(postID), userID).Result(); err == nil && score > 0 {
+				createdAt = time.UnixMilli(int64(score))
+			}
+			likes = append(likes, db.Like{PostID: postID, UserID: userID, CreatedAt: createdAt})
+		}
+		added, err := s.likeRepo().Import(ctx, likes)
+		if err != nil {
+			return fmt.Errorf("failed to import likes of post %s: %v", postID, err)
+		}
+		imported += added
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("🟢 Imported %d likes from Redis into Postgres", imported)
+	return nil
+}
+
+// likesRebuildLockKey is the lease on rebuilding the likes cache, so that
+// replicas starting together don't all rebuild it.
+const (
+	likesRebuildLockKey = "likes:rebuild:lock"
+	likesRebuildLease   = 10 * time.Minute
+)
+
+// fillLikesScript caches a post's likes unless they are cached already, like
+// Fill: the hash is written at once with the absolute count, so it is never
+// seen partly filled and writing it twice changes nothing.
+//
+//	KEYS[1] likes hash   KEYS[2] likers zset
+//	ARGV[1] total        ARGV[2..] user id, score pairs
+var fillLikesScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], 'total-likes') == 1 then
+	return 0
+end
+local fields = {'total-likes', ARGV[1]}
+for i = 2, #ARGV, 2 do
+	fields[#fields + 1] = ARGV[i]
+	fields[#fields + 1] = 1
+	redis.call('ZADD', KEYS[2], ARGV[i + 1], ARGV[i])
+end
+redis.call('HSET', KEYS[1], unpack(fields))
+return 1
+`)
+
+// rebuildLikesCache writes every like in Postgres back into Redis, one post
+// at a time. Only one replica rebuilds, the others fill posts on first use
+// meanwhile, and posts cached that way are left alone.
+func rebuildLikesCache(s *Server, rdb redis.UniversalClient, ctx context.Context) error {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	ok, err := rdb.SetNX(ctx, likesRebuildLockKey, token, likesRebuildLease).Result()
+	if err != nil {
+		return fmt.Errorf("failed to take the likes rebuild lock: %v", err)
+	}
+	if !ok {
+		log.Println("🟢 Likes cache is being rebuilt by another replica")
+		return nil
+	}
+	defer func() {
+		err := releaseLockScript.Run(context.WithoutCancel(ctx), rdb, []string{likesRebuildLockKey}, token).Err()
+		if err != nil {
+			log.Printf("🔴 Failed to release likes rebuild lock: %v", err)
+		}
+	}()
+
+	rebuilt, posts := 0, 0
+	pipe := rdb.Pipeline()
+	var postID string
+	var likes []db.Like
+	flush := func() error {
+		if len(likes) == 0 {
+			return nil
+		}
+		args := []interface{}{len(likes)}
+		for _, like := range likes {
+			score := like.CreatedAt.UnixMilli()
+			args = append(args, like.UserID, score)
+			pipe.ZAdd(ctx, userLikedKey(like.UserID), &redis.Z{Score: float64(score), Member: like.PostID})
+		}
+		// Eval, EVALSHA can't fall back to sending the script in a pipeline.
+		fillLikesScript.Eval(ctx, pipe, []string{postLikesKey(postID), postLikersKey(postID)}, args...)
+		rebuilt += len(likes)
+		posts++
+		likes = likes[:0]
+
+		if posts%likesSyncBatchSize == 0 {
+			_, err := pipe.Exec(ctx)
+			return err
+		}
+		return nil
+	}
+
+	err = s.likeRepo().Each(ctx, func(like db.Like) error {
+		if like.PostID != postID {
+			if err := flush(); err != nil {
+				return err
+			}
+			postID = like.PostID
+		}
+		likes = append(likes, like)
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err == nil {
+		_, err = pipe.Exec(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rebuild likes cache: %v", err)
+	}
+
+	log.Printf("🟢 Rebuilt %d likes of %d posts in Redis from Postgres", rebuilt, posts)
+	return nil
+}
+
+// LoadLikes caches the likes of posts that Redis doesn't know yet, e.g.
+// freshly generated ones, in one pipeline instead of a transaction per post.
+// Every post in postIDs is cached, posts without likes with a count of 0.
+func LoadLikes(ctx context.Context, rdb redis.UniversalClient, postIDs []string, likes []db.Like) error {
+	likesByPost := make(map[string][]db.Like, len(postIDs))
+	for _, like := range likes {
+		likesByPost[like.PostID] = append(likesByPost[like.PostID], like)
+	}
+
+	pipe := rdb.Pipeline()
+	for _, postID := range postIDs {
+		postLikes := likesByPost[postID]
+		fields := []interface{}{"total-likes", len(postLikes)}
+		likers := make([]*redis.Z, len(postLikes))
+		for i, like := range postLikes {
+			score := float64(like.CreatedAt.UnixMilli())
+			fields = append(fields, like.UserID, true)
+			likers[i] = &redis.Z{Score: score, Member: like.UserID}
+			pipe.ZAdd(ctx, userLikedKey(like.UserID), &redis.Z{Score: score, Member: postID})
+		}
+		pipe.HSet(ctx, postLikesKey(postID), fields...)
+		if len(likers) > 0 {
+			pipe.ZAdd(ctx, postLikersKey(postID), likers...)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cache likes: %v", err)
+	}
+	return nil
+}
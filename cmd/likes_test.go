@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) *redis.Client {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+func TestMigrateLikeIndexes(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+
+	rdb.HSet(ctx, postLikesKey("post-1"), "total-likes", 2, "user-1", true, "user-2", true)
+	rdb.HSet(ctx, postLikesKey("post-2"), "total-likes", 1, "user-1", true)
+	rdb.HSet(ctx, postLikesKey("post-3"), "total-likes", 0)
+
+	require.NoError(t, MigrateLikeIndexes(ctx, rdb))
+
+	likers, err := rdb.ZRange(ctx, postLikersKey("post-1"), 0, -1).Result()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"user-1", "user-2"}, likers)
+
+	liked, err := rdb.ZRange(ctx, userLikedKey("user-1"), 0, -1).Result()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"post-1", "post-2"}, liked)
+
+	// A second run is a no-op even if new hashes showed up in between.
+	rdb.HSet(ctx, postLikesKey("post-3"), "user-3", true)
+	require.NoError(t, MigrateLikeIndexes(ctx, rdb))
+	require.Zero(t, rdb.Exists(ctx, userLikedKey("user-3")).Val())
+}
+
+func TestToggleLikeOrdersNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	store := NewRedisLikeStore(newTestRedis(t))
+	rdb := store.rdb
+	require.NoError(t, store.Fill(ctx, "post-1", nil))
+	require.NoError(t, store.Fill(ctx, "post-2", nil))
+
+	_, _, _, err := store.Set(ctx, "post-1", "user-1", LikeToggle)
+	require.NoError(t, err)
+	rdb.ZIncrBy(ctx, userLikedKey("user-1"), -1000, "post-1")
+	_, _, _, err = store.Set(ctx, "post-2", "user-1", LikeToggle)
+	require.NoError(t, err)
+
+	start, stop := listWindow(0, 0)
+	liked, err := rdb.ZRevRange(ctx, userLikedKey("user-1"), start, stop).Result()
+	require.NoError(t, err)
+	require.Equal(t, []string{"post-2", "post-1"}, liked)
+
+	isLiked, total, _, err := store.Set(ctx, "post-2", "user-1", LikeToggle)
+	require.NoError(t, err)
+	require.False(t, isLiked)
+	require.Zero(t, total)
+	require.Equal(t, []string{"post-1"}, rdb.ZRevRange(ctx, userLikedKey("user-1"), 0, -1).Val())
+	require.Empty(t, rdb.ZRange(ctx, postLikersKey("post-2"), 0, -1).Val())
+}
+
+func TestConcurrentToggleLikeKeepsCountConsistent(t *testing.T) {
+	ctx := context.Background()
+	store := NewRedisLikeStore(newTestRedis(t))
+	rdb := store.rdb
+	require.NoError(t, store.Fill(ctx, "post-1", nil))
+
+	const (
+		users   = 20
+		toggles = 25 // odd, so every user ends up liking the post
+	)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, users*toggles)
+	for u := 0; u < users; u++ {
+		userID := fmt.Sprintf("user-%d", u)
+		// Two goroutines per user simulate double taps racing each other.
+		for half := 0; half < 2; half++ {
+			n := toggles / 2
+			if half == 0 {
+				n = toggles - n
+			}
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				for i := 0; i < n; i++ {
+					if _, _, _, err := store.Set(ctx, "post-1", userID, LikeToggle); err != nil {
+						errs <- err
+					}
+				}
+			}(n)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	fields := rdb.HGetAll(ctx, postLikesKey("post-1")).Val()
+	total, err := strconv.Atoi(fields["total-likes"])
+	require.NoError(t, err)
+	require.Equal(t, len(fields)-1, total)
+	require.Equal(t, users, total)
+	require.EqualValues(t, users, rdb.ZCard(ctx, postLikersKey("post-1")).Val())
+	for u := 0; u < users; u++ {
+		require.EqualValues(t, 1, rdb.ZCard(ctx, userLikedKey(fmt.Sprintf("user-%d", u))).Val())
+	}
+}
+
+func TestLikeAndUnlikeAreIdempotent(t *testing.T) {
+	ctx := context.Background()
+	store := NewRedisLikeStore(newTestRedis(t))
+	require.NoError(t, store.Fill(ctx, "post-1", nil))
+
+	for i, want := range []bool{true, false, false} {
+		liked, total, changed, err := store.Set(ctx, "post-1", "user-1", LikeSet)
+		require.NoError(t, err)
+		require.True(t, liked)
+		require.EqualValues(t, 1, total)
+		require.Equal(t, want, changed, "like #%d", i+1)
+	}
+
+	for i, want := range []bool{true, false, false} {
+		liked, total, changed, err := store.Set(ctx, "post-1", "user-1", LikeUnset)
+		require.NoError(t, err)
+		require.False(t, liked)
+		require.Zero(t, total)
+		require.Equal(t, want, changed, "unlike #%d", i+1)
+	}
+}
+
+func TestLoadLikes(t *testing.T) {
+	ctx := context.Background()
+	store := NewRedisLikeStore(newTestRedis(t))
+	at := time.Date(2025, 3, 26, 13, 11, 0, 0, time.UTC)
+
+	require.NoError(t, LoadLikes(ctx, store.rdb, []string{"post-1", "post-2"}, []db.Like{
+		{PostID: "post-1", UserID: "user-1", CreatedAt: at},
+		{PostID: "post-1", UserID: "user-2", CreatedAt: at.Add(time.Minute)},
+	}))
+
+	missing, err := store.Missing(ctx, []string{"post-1", "post-2", "post-3"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"post-3"}, missing)
+
+	counts, err := store.Counts(ctx, "user-2", []string{"post-1", "post-2"})
+	require.NoError(t, err)
+	require.Equal(t, LikeCount{Total: 2, Liked: true}, counts["post-1"])
+	require.Equal(t, LikeCount{}, counts["post-2"])
+
+	likers, err := store.Likers(ctx, "post-1", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"user-2", "user-1"}, likers)
+	liked, err := store.Liked(ctx, "user-1", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"post-1"}, liked)
+}
+
+func TestRebuildLikesCache(t *testing.T) {
+	ctx := context.Background()
+	users := NewMemoryUserRepository()
+	posts := NewMemoryPostRepository(users)
+	likes := NewMemoryLikeRepository(posts)
+	store := NewRedisLikeStore(newTestRedis(t))
+	s := &Server{Posts: posts, Users: users, LikeRepo: likes, Likes: store}
+	at := time.Date(2025, 3, 26, 13, 11, 0, 0, time.UTC)
+	_, err := likes.Import(ctx, []db.Like{
+		{PostID: "post-1", UserID: "user-1", CreatedAt: at},
+		{PostID: "post-1", UserID: "user-2", CreatedAt: at.Add(time.Minute)},
+		{PostID: "post-2", UserID: "user-1", CreatedAt: at},
+	})
+	require.NoError(t, err)
+
+	// A post cached on first use meanwhile is newer than the rebuild.
+	require.NoError(t, store.Fill(ctx, "post-2", nil))
+
+	// Rebuilding again, e.g. on a replica starting at the same time, doesn't
+	// count anything twice.
+	require.NoError(t, rebuildLikesCache(s, store.rdb, ctx))
+	require.NoError(t, rebuildLikesCache(s, store.rdb, ctx))
+
+	counts, err := store.Counts(ctx, "user-2", []string{"post-1", "post-2"})
+	require.NoError(t, err)
+	require.Equal(t, LikeCount{Total: 2, Liked: true}, counts["post-1"])
+	require.Equal(t, LikeCount{}, counts["post-2"])
+	likers, err := store.Likers(ctx, "post-1", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"user-2", "user-1"}, likers)
+	liked, err := store.Liked(ctx, "user-1", 0, -1)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"post-1", "post-2"}, liked)
+
+	// Only the replica holding the lease rebuilds.
+	_, err = likes.Import(ctx, []db.Like{{PostID: "post-3", UserID: "user-1", CreatedAt: at}})
+	require.NoError(t, err)
+	store.rdb.Set(ctx, likesRebuildLockKey, "another replica", time.Minute)
+	require.NoError(t, rebuildLikesCache(s, store.rdb, ctx))
+	missing, err := store.Missing(ctx, []string{"post-3"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"post-3"}, missing)
+}
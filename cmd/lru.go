@@ -0,0 +1,84 @@
+package server
+
+import (
+	"container/list"
+	"time"
+)
+
+// lru is a map bounded to capacity entries that evicts the least recently
+// used one, with an optional time to live. It is not safe for concurrent use.
+type lru[K comparable, V any] struct {
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	items    map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// newLRU returns an lru of the given capacity. A zero ttl keeps entries until
+// they are evicted.
+func newLRU[K comparable, V any](capacity int, ttl time.Duration) *lru[K, V] {
+	return &lru[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[K]*list.Element),
+	}
+}
+
+func (c *lru[K, V]) Get(key K) (V, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	entry := el.Value.(*lruEntry[K, V])
+	if c.ttl > 0 && time.Now().After(entry.expires) {
+		c.remove(el)
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *lru[K, V]) Add(key K, value V) {
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*lruEntry[K, V])
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *lru[K, V]) Remove(key K) {
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Purge removes every entry.
+func (c *lru[K, V]) Purge() {
+	c.order.Init()
+	clear(c.items)
+}
+
+func (c *lru[K, V]) Len() int {
+	return c.order.Len()
+}
+
+func (c *lru[K, V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*lruEntry[K, V]).key)
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// Notification kinds as stored in Postgres. Likes are the only engagement
+// the API has today; a new one adds its kind here, to Notification.Kind in
+// the proto and to notificationVerbs, and calls notify.
+const (
+	notificationPostLiked = "post_liked"
+)
+
+var notificationKinds = map[string]blog.Notification_Kind{
+	notificationPostLiked: blog.Notification_POST_LIKED,
+}
+
+// notificationVerbs completes "<actor> ... your post" for each kind.
+var notificationVerbs = map[string]string{
+	notificationPostLiked: "liked",
+}
+
+// Postgres is the source of truth for notifications, Redis only keeps the
+// unread counter at user:<id>:notifications:unread. The counter is adjusted
+// only while it exists; a missing counter is recounted from Postgres on read.
+// Adjustments lost while Redis was unreachable are corrected by the recount
+// after marking notifications read, or once the counter expires. A server
+// without Redis counts from Postgres on every read.
+const unreadCountTTL = 10 * time.Minute
+
+func unreadNotificationsKey(userID string) string {
+	return "user:" + userID + ":notifications:unread"
+}
+
+// adjustCounterScript adds ARGV[1] to an existing counter, never going below
+// zero.
+//
+//	KEYS[1] counter   ARGV[1] delta
+var adjustCounterScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return nil
+end
+local n = redis.call('INCRBY', KEYS[1], ARGV[1])
+if n < 0 then
+  redis.call('SET', KEYS[1], 0)
+end
+return n
+`)
+
+type NotificationServer struct {
+	blog.UnimplementedNotificationServiceServer
+	*Server
+}
+
+func NewNotificationServer(s *Server) *NotificationServer {
+	return &NotificationServer{Server: s}
+}
+
+// notificationText renders e.g. "kaneki_ken and 4 others liked your post".
+func notificationText(n *db.Notification) string {
+	text := n.Actor.NickName
+	switch others := n.ActorCount - 1; {
+	case others == 1:
+		text += " and 1 other"
+	case others > 1:
+		text += fmt.Sprintf(" and %d others", others)
+	}
+	return text + " " + notificationVerbs[n.Kind] + " your post"
+}
+
+func dbNotificationToProtoNotification(n *db.Notification) *blog.Notification {
+	others := int32(n.ActorCount - 1)
+	if others < 0 {
+		others = 0
+	}
+	return &blog.Notification{
+		Id:          n.ID,
+		Kind:        notificationKinds[n.Kind],
+		PostId:      n.PostID,
+		Actor:       dbUserToProtoUser(&n.Actor),
+		OthersCount: others,
+		Text:        notificationText(n),
+		Read:        n.Read,
+		UpdatedAt:   n.UpdatedAt.Format("15:04:05 02.01.2006"),
+	}
+}
+
+// notify records that actorID did kind on the recipient's post, folding it
+// into the recipient's unread notification for the post if there is one.
+// Like feed events, notifications are best effort and never fail the request
+// that caused them.
+func (s *Server) notify(ctx context.Context, recipientID, actorID, kind, postID string) {
+	if recipientID == actorID {
+		return
+	}
+
+	now := time.Now()
+	created, err := s.notificationRepo().Add(ctx, &db.Notification{
+		ID:          s.newID("notification-"),
+		RecipientID: recipientID,
+		Kind:        kind,
+		PostID:      postID,
+		ActorID:     actorID,
+		ActorCount:  1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		log.Printf("🔴 Failed to notify %s about %s of post %s: %v", recipientID, kind, postID, err)
+		return
+	}
+
+	if created {
+		s.adjustUnreadCount(ctx, recipientID, 1)
+	}
+}
+
+func (s *Server) adjustUnreadCount(ctx context.Context, userID string, delta int64) {
+	if s.Redis_DB == nil {
+		return
+	}
+	err := adjustCounterScript.Run(ctx, s.Redis_DB, []string{unreadNotificationsKey(userID)}, delta).Err()
+	if err != nil && err != redis.Nil {
+		log.Printf("🔴 Failed to update unread notifications of %s: %v", userID, err)
+	}
+}
+
+// recountUnread resets the unread counter from Postgres.
+func (s *Server) recountUnread(ctx context.Context, userID string) {
+	if s.Redis_DB == nil {
+		return
+	}
+	count, err := s.notificationRepo().CountUnread(ctx, userID)
+	if err == nil {
+		err = s.Redis_DB.Set(ctx, unreadNotificationsKey(userID), count, unreadCountTTL).Err()
+	}
+	if err != nil {
+		log.Printf("🔴 Failed to recount unread notifications of %s: %v", userID, err)
+	}
+}
+
+// dropPostNotifications removes the notifications about a deleted post. It
+// runs inside the transaction deleting the post.
+func dropPostNotifications(tx *gorm.DB, postID string) error {
+	ids := tx.Model(&db.Notification{}).Select("id").Where("post_id = ?", postID)
+	if err := tx.Where("notification_id IN (?)", ids).Delete(&db.NotificationActor{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("post_id = ?", postID).Delete(&db.Notification{}).Error
+}
+
+func (s *NotificationServer) ListNotifications(ctx context.Context, req *blog.ListNotificationsRequest) (*blog.ListNotificationsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start, stop := listWindow(req.Limit, req.Offset)
+	notifications, err := s.notificationRepo().List(ctx, userID, req.UnreadOnly, int(start), int(stop-start+1))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get notifications: %v", err)
+	}
+
+	resp := &blog.ListNotificationsResponse{}
+	for i := range notifications {
+		resp.Notifications = append(resp.Notifications, dbNotificationToProtoNotification(&notifications[i]))
+	}
+	return resp, nil
+}
+
+func (s *NotificationServer) MarkRead(ctx context.Context, req *blog.MarkReadRequest) (*blog.MarkReadResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	marked, err := s.notificationRepo().MarkRead(ctx, req.Id, userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to mark notification as read: %v", err)
+	}
+	if marked {
+		s.recountUnread(ctx, userID)
+	}
+
+	n, err := s.notificationRepo().Get(ctx, req.Id, userID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "notification not found: %s", req.Id)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch notification: %v", err)
+	}
+	return &blog.MarkReadResponse{Notification: dbNotificationToProtoNotification(n)}, nil
+}
+
+func (s *NotificationServer) MarkAllRead(ctx context.Context, req *blog.MarkAllReadRequest) (*blog.MarkAllReadResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	marked, err := s.notificationRepo().MarkAllRead(ctx, userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to mark notifications as read: %v", err)
+	}
+	s.recountUnread(ctx, userID)
+
+	return &blog.MarkAllReadResponse{MarkedCount: int32(marked)}, nil
+}
+
+func (s *NotificationServer) GetUnreadCount(ctx context.Context, req *blog.GetUnreadCountRequest) (*blog.GetUnreadCountResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Redis_DB != nil {
+		count, err := s.Redis_DB.Get(ctx, unreadNotificationsKey(userID)).Int64()
+		if err == nil {
+			return &blog.GetUnreadCountResponse{Count: int32(count)}, nil
+		}
+		if err != redis.Nil {
+			log.Printf("🔴 Failed to read unread notifications of %s: %v", userID, err)
+		}
+	}
+
+	count, err := s.notificationRepo().CountUnread(ctx, userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to count notifications: %v", err)
+	}
+	if s.Redis_DB != nil {
+		// Only fill a missing counter, one may have been created meanwhile.
+		s.Redis_DB.SetNX(ctx, unreadNotificationsKey(userID), count, unreadCountTTL)
+	}
+
+	return &blog.GetUnreadCountResponse{Count: int32(count)}, nil
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"gorm.io/gorm"
+)
+
+func TestNotificationText(t *testing.T) {
+	tests := []struct {
+		actors int
+		want   string
+	}{
+		{1, "kaneki_ken liked your post"},
+		{2, "kaneki_ken and 1 other liked your post"},
+		{5, "kaneki_ken and 4 others liked your post"},
+	}
+
+	for _, tt := range tests {
+		n := &db.Notification{
+			Kind:       notificationPostLiked,
+			Actor:      db.User{NickName: "kaneki_ken"},
+			ActorCount: tt.actors,
+		}
+		require.Equal(t, tt.want, notificationText(n))
+	}
+}
+
+func TestAdjustUnreadCount(t *testing.T) {
+	ctx := context.Background()
+	s := &Server{Redis_DB: newTestRedis(t)}
+	key := unreadNotificationsKey("user-1")
+
+	// A missing counter is left for GetUnreadCount to recount.
+	s.adjustUnreadCount(ctx, "user-1", 1)
+	require.Zero(t, s.Redis_DB.Exists(ctx, key).Val())
+
+	s.Redis_DB.Set(ctx, key, 1, 0)
+	s.adjustUnreadCount(ctx, "user-1", 1)
+	require.Equal(t, "2", s.Redis_DB.Get(ctx, key).Val())
+
+	s.adjustUnreadCount(ctx, "user-1", -5)
+	require.Equal(t, "0", s.Redis_DB.Get(ctx, key).Val())
+}
+
+func TestNotifyFoldsActorsOnSQLite(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := newTestDatabase(t)
+	s := &Server{Sql_DB: sqlDB, Redis_DB: newTestRedis(t)}
+	for _, id := range []string{"user-1", "user-2", "user-3"} {
+		require.NoError(t, s.userRepo().Create(ctx, &db.User{ID: id, NickName: "nick-" + id}))
+	}
+	require.NoError(t, s.postRepo().Create(ctx, &db.Post{ID: "post-1", AuthorID: "user-1", Body: "Post 1"}, nil))
+
+	// Liking, unliking and liking again counts once.
+	s.notify(ctx, "user-1", "user-2", notificationPostLiked, "post-1")
+	s.notify(ctx, "user-1", "user-2", notificationPostLiked, "post-1")
+	s.notify(ctx, "user-1", "user-3", notificationPostLiked, "post-1")
+
+	var notifications []db.Notification
+	require.NoError(t, sqlDB.Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	require.Equal(t, 2, notifications[0].ActorCount)
+	require.Equal(t, "user-3", notifications[0].ActorID)
+
+	// A missing counter is filled from Postgres and expires.
+	key := unreadNotificationsKey("user-1")
+	userCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("user-id", "user-1"))
+	unread, err := NewNotificationServer(s).GetUnreadCount(userCtx, &blog.GetUnreadCountRequest{})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, unread.Count)
+	require.Positive(t, s.Redis_DB.TTL(ctx, key).Val())
+
+	// A counter that drifted, e.g. while Redis was down, is recounted.
+	s.Redis_DB.Set(ctx, key, 7, 0)
+	resp, err := NewNotificationServer(s).MarkAllRead(userCtx, &blog.MarkAllReadRequest{})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, resp.MarkedCount)
+	require.Equal(t, "0", s.Redis_DB.Get(ctx, key).Val())
+	require.Positive(t, s.Redis_DB.TTL(ctx, key).Val())
+}
+
+func TestUnreadCountWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	s := &Server{Sql_DB: newTestDatabase(t)}
+	for _, id := range []string{"user-1", "user-2"} {
+		require.NoError(t, s.userRepo().Create(ctx, &db.User{ID: id, NickName: "nick-" + id}))
+	}
+	require.NoError(t, s.postRepo().Create(ctx, &db.Post{ID: "post-1", AuthorID: "user-1", Body: "Post 1"}, nil))
+	s.notify(ctx, "user-1", "user-2", notificationPostLiked, "post-1")
+
+	userCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("user-id", "user-1"))
+	unread, err := NewNotificationServer(s).GetUnreadCount(userCtx, &blog.GetUnreadCountRequest{})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, unread.Count)
+
+	_, err = NewNotificationServer(s).MarkAllRead(userCtx, &blog.MarkAllReadRequest{})
+	require.NoError(t, err)
+	unread, err = NewNotificationServer(s).GetUnreadCount(userCtx, &blog.GetUnreadCountRequest{})
+	require.NoError(t, err)
+	require.Zero(t, unread.Count)
+}
+
+func TestConcurrentNotifyFoldsIntoOneNotification(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := newTestDatabase(t)
+	s := &Server{Sql_DB: sqlDB, Redis_DB: newTestRedis(t)}
+	const likers = 8
+	require.NoError(t, s.userRepo().Create(ctx, &db.User{ID: "user-0", NickName: "nick-user-0"}))
+	for i := 1; i <= likers; i++ {
+		id := fmt.Sprintf("user-%d", i)
+		require.NoError(t, s.userRepo().Create(ctx, &db.User{ID: id, NickName: "nick-" + id}))
+	}
+	require.NoError(t, s.postRepo().Create(ctx, &db.Post{ID: "post-1", AuthorID: "user-0", Body: "Post 1"}, nil))
+	s.Redis_DB.Set(ctx, unreadNotificationsKey("user-0"), 0, 0)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= likers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.notify(ctx, "user-0", fmt.Sprintf("user-%d", i), notificationPostLiked, "post-1")
+		}()
+	}
+	wg.Wait()
+
+	var notifications []db.Notification
+	require.NoError(t, sqlDB.Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	require.Equal(t, likers, notifications[0].ActorCount)
+	require.Equal(t, "1", s.Redis_DB.Get(ctx, unreadNotificationsKey("user-0")).Val())
+}
+
+func TestNotifyFallsBackToUpdateOnConflict(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := newTestDatabase(t)
+	s := &Server{Sql_DB: sqlDB, Redis_DB: newTestRedis(t)}
+	for _, id := range []string{"user-1", "user-2", "user-3"} {
+		require.NoError(t, s.userRepo().Create(ctx, &db.User{ID: id, NickName: "nick-" + id}))
+	}
+	require.NoError(t, s.postRepo().Create(ctx, &db.Post{ID: "post-1", AuthorID: "user-1", Body: "Post 1"}, nil))
+
+	// user-3's notification shows up right after user-2's lookup missed it.
+	raced := false
+	require.NoError(t, sqlDB.Callback().Query().After("gorm:query").Register("test:race", func(tx *gorm.DB) {
+		if raced || tx.Statement.Table != "notifications" {
+			return
+		}
+		raced = true
+		now := time.Now()
+		// Inside the transaction, without the lookup's ErrRecordNotFound.
+		other := tx.Session(&gorm.Session{NewDB: true})
+		other.Error = nil
+		require.NoError(t, other.Create(&db.Notification{
+			ID: "notification-3", RecipientID: "user-1", Kind: notificationPostLiked, PostID: "post-1",
+			ActorID: "user-3", ActorCount: 1, CreatedAt: now, UpdatedAt: now,
+		}).Error)
+		require.NoError(t, other.Create(&db.NotificationActor{NotificationID: "notification-3", ActorID: "user-3"}).Error)
+	}))
+	s.notify(ctx, "user-1", "user-2", notificationPostLiked, "post-1")
+	require.True(t, raced)
+
+	var notifications []db.Notification
+	require.NoError(t, sqlDB.Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	require.Equal(t, 2, notifications[0].ActorCount)
+	require.Equal(t, "user-2", notifications[0].ActorID)
+
+	// Read notifications don't count against the index.
+	require.NoError(t, sqlDB.Model(&db.Notification{}).Where("id = ?", "notification-3").Update("read", true).Error)
+	s.notify(ctx, "user-1", "user-3", notificationPostLiked, "post-1")
+	require.NoError(t, sqlDB.Find(&notifications).Error)
+	require.Len(t, notifications, 2)
+}
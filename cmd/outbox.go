@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Mutations never publish events themselves. They add an outbox row in the
+// same transaction as the change, and the relay publishes unsent rows to the
+// feed:events stream and to webhooks in id order before marking them sent.
+// Publishing is at-least-once: if the relay dies after publishing but before
+// marking, the event is published again.
+
+const (
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxRetention    = 24 * time.Hour
+	outboxBatchSize           = 100
+)
+
+// writeOutbox adds the event to the outbox as part of tx.
+func writeOutbox(tx *gorm.DB, event *blog.FeedEvent) error {
+	payload, err := proto.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&db.OutboxEvent{
+		Type:      event.Type.String(),
+		PostID:    event.PostId,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}).Error
+}
+
+// lockForUpdate makes tx lock the rows it selects until it ends. Without row
+// locks the transaction already holds the database's only write lock.
+func lockForUpdate(tx *gorm.DB) *gorm.DB {
+	if !db.DialectOf(tx).RowLocks {
+		return tx
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// OutboxRelay publishes outbox events.
+type OutboxRelay struct {
+	sqlDB    *gorm.DB
+	feed     *FeedHub
+	webhooks *WebhookDispatcher
+	wake     chan struct{}
+
+	PollInterval time.Duration
+	// Retention is how long sent events are kept before being purged.
+	Retention time.Duration
+}
+
+func NewOutboxRelay(sqlDB *gorm.DB, feed *FeedHub, webhooks *WebhookDispatcher) *OutboxRelay {
+	return &OutboxRelay{
+		sqlDB:        sqlDB,
+		feed:         feed,
+		webhooks:     webhooks,
+		wake:         make(chan struct{}, 1),
+		PollInterval: defaultOutboxPollInterval,
+		Retention:    defaultOutboxRetention,
+	}
+}
+
+// Notify tells the relay that events were committed, so they go out without
+// waiting for the next poll.
+func (r *OutboxRelay) Notify() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes outbox events until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	lastPurge := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+
+		for {
+			n, err := r.relayBatch(ctx)
+			if err != nil {
+				log.Printf("🔴 Failed to relay outbox events: %v", err)
+			}
+			if err != nil || n < outboxBatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastPurge) > time.Hour {
+			lastPurge = time.Now()
+			if err := r.purgeSent(ctx); err != nil {
+				log.Printf("🔴 Failed to purge sent outbox events: %v", err)
+			}
+		}
+	}
+}
+
+// relayBatch publishes the oldest unsent events. The rows stay locked until
+// they are marked sent, so relays on other replicas wait instead of
+// publishing them again or out of order.
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	var published int
+	var publishErr error
+
+	err := r.sqlDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var events []db.OutboxEvent
+		err := lockForUpdate(tx).
+			Where("sent_at IS NULL").
+			Order("id").Limit(outboxBatchSize).
+			Find(&events).Error
+		if err != nil {
+			return err
+		}
+
+		var sent []uint64
+		for i := range events {
+			if publishErr = r.publish(ctx, &events[i]); publishErr != nil {
+				// Keep the order: the rest waits until this one goes out.
+				break
+			}
+			sent = append(sent, events[i].ID)
+		}
+		if len(sent) == 0 {
+			return nil
+		}
+		published = len(sent)
+		return tx.Model(&db.OutboxEvent{}).Where("id IN ?", sent).Update("sent_at", time.Now()).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, row *db.OutboxEvent) error {
+	event := &blog.FeedEvent{}
+	if err := proto.Unmarshal(row.Payload, event); err != nil {
+		// Retrying won't help, skip it rather than block the outbox.
+		log.Printf("🔴 Dropping undecodable outbox event %d: %v", row.ID, err)
+		return nil
+	}
+
+	if r.feed != nil {
+		id, err := r.feed.Publish(ctx, event)
+		if err != nil {
+			return err
+		}
+		event.Id = id
+	}
+	if r.webhooks != nil {
+		if err := r.webhooks.Enqueue(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *OutboxRelay) purgeSent(ctx context.Context) error {
+	return r.sqlDB.WithContext(ctx).
+		Where("sent_at < ?", time.Now().Add(-r.Retention)).
+		Delete(&db.OutboxEvent{}).Error
+}
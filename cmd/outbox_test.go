@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	blog "go_grpc_blog/api"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestOutboxRelayPublishesInOrderAndMarksSent(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	require.NoError(t, err)
+
+	created, _ := proto.Marshal(&blog.FeedEvent{Type: blog.FeedEvent_POST_CREATED, PostId: "post-1"})
+	deleted, _ := proto.Marshal(&blog.FeedEvent{Type: blog.FeedEvent_POST_DELETED, PostId: "post-1"})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "outbox_events" WHERE sent_at IS NULL ORDER BY id LIMIT \$1 FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "post_id", "payload"}).
+			AddRow(1, "POST_CREATED", "post-1", created).
+			AddRow(2, "POST_DELETED", "post-1", deleted))
+	mock.ExpectExec(`UPDATE "outbox_events" SET "sent_at"=\$1 WHERE id IN \(\$2,\$3\)`).
+		WithArgs(sqlmock.AnyArg(), 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	hub := NewFeedHub(newTestRedis(t))
+	relay := NewOutboxRelay(gormDB, hub, nil)
+	n, err := relay.relayBatch(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	msgs, err := hub.rdb.XRange(ctx, feedEventsKey, "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	events, err := hub.eventsAfter(ctx, msgs[0].ID)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, blog.FeedEvent_POST_DELETED, events[0].Type)
+}
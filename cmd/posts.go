@@ -0,0 +1,306 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Handler logic shared by every API version. The versioned handlers only deal
+// with authentication and converting the results to their own messages.
+
+// likedPost is a post together with its likes as seen by a particular user.
+// LikesUnknown is set instead while the like store is unavailable.
+type likedPost struct {
+	db.Post
+	LikesCount   int32
+	IsLiked      bool
+	LikesUnknown bool
+}
+
+// getPosts returns a page of the newest posts. Pages within the cached window
+// are served from Redis, deeper ones from Postgres.
+func (s *Server) getPosts(ctx context.Context, userID string, limit, offset int) ([]likedPost, error) {
+	start, stop := listWindow(int32(limit), int32(offset))
+
+	dbPosts, ok, err := cachedPage(s, ctx, start, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		dbPosts, err = s.postRepo().Newest(ctx, int(start), int(stop-start+1))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to fetch posts: %v", err)
+		}
+	}
+
+	return s.attachLikes(ctx, userID, dbPosts)
+}
+
+func (s *Server) createPost(ctx context.Context, authorID, body string) (*db.Post, error) {
+	user, err := s.userRepo().Get(ctx, authorID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "user not found: %s", authorID)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch user: %v", err)
+	}
+
+	newPost := db.Post{
+		ID:        s.newID("post-"),
+		AuthorID:  user.ID,
+		Author:    *user,
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+
+	err = s.postRepo().Create(ctx, &newPost, &blog.FeedEvent{
+		Type:   blog.FeedEvent_POST_CREATED,
+		PostId: newPost.ID,
+		Post:   dbPostToProtoPost(&newPost, authorID),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create post: %v", err)
+	}
+	s.Outbox.Notify()
+	s.invalidateFeedCache(ctx)
+
+	return &newPost, nil
+}
+
+// postUpdate carries the new values for the fields named in an update mask.
+type postUpdate struct {
+	Body string
+}
+
+// postFieldSetters lists the post fields clients may change, keyed by their
+// proto field name as used in update_mask paths.
+var postFieldSetters = map[string]func(dst *db.Post, src *postUpdate){
+	"body": func(dst *db.Post, src *postUpdate) { dst.Body = src.Body },
+}
+
+// immutablePostFields are post fields that exist but can never be updated.
+var immutablePostFields = map[string]bool{
+	"id":          true,
+	"author":      true,
+	"created_at":  true,
+	"likes_count": true,
+	"is_liked":    true,
+}
+
+// postUpdateSetters validates update_mask paths and returns the setters to
+// apply. An empty mask means every updatable field.
+func postUpdateSetters(paths []string) ([]func(*db.Post, *postUpdate), error) {
+	if len(paths) == 0 {
+		setters := make([]func(*db.Post, *postUpdate), 0, len(postFieldSetters))
+		for _, set := range postFieldSetters {
+			setters = append(setters, set)
+		}
+		return setters, nil
+	}
+
+	setters := make([]func(*db.Post, *postUpdate), 0, len(paths))
+	for _, path := range paths {
+		field, _, _ := strings.Cut(path, ".")
+		if immutablePostFields[field] {
+			return nil, status.Errorf(codes.InvalidArgument, "field %q is immutable", path)
+		}
+		set, ok := postFieldSetters[path]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown field %q in update_mask", path)
+		}
+		setters = append(setters, set)
+	}
+	return setters, nil
+}
+
+func (s *Server) updatePost(ctx context.Context, currentUserID, id string, update *postUpdate, paths []string) (*db.Post, error) {
+	setters, err := postUpdateSetters(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	dbPost, err := s.getPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbPost.Author.ID != currentUserID {
+		return nil, status.Error(codes.PermissionDenied, "only author can update the post")
+	}
+
+	for _, set := range setters {
+		set(dbPost, update)
+	}
+	err = s.postRepo().Update(ctx, dbPost, &blog.FeedEvent{
+		Type:   blog.FeedEvent_POST_UPDATED,
+		PostId: dbPost.ID,
+		Post:   dbPostToProtoPost(dbPost, currentUserID),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update post: %v", err)
+	}
+	s.Outbox.Notify()
+	s.invalidateFeedCache(ctx)
+	s.forgetPost(ctx, dbPost.ID)
+
+	return dbPost, nil
+}
+
+// getPost loads a post with its author, bypassing the caches.
+func (s *Server) getPost(ctx context.Context, id string) (*db.Post, error) {
+	dbPost, err := s.postRepo().Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "post not found: %s", id)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch post: %v", err)
+	}
+	return dbPost, nil
+}
+
+func (s *Server) deletePost(ctx context.Context, currentUserID, id string) error {
+	dbPost, err := s.getPost(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if dbPost.Author.ID != currentUserID {
+		return status.Error(codes.PermissionDenied, "only author can delete the post")
+	}
+
+	err = s.postRepo().Delete(ctx, id, &blog.FeedEvent{
+		Type:   blog.FeedEvent_POST_DELETED,
+		PostId: id,
+	})
+	if errors.Is(err, ErrNotFound) {
+		return status.Errorf(codes.NotFound, "post not found: %s", id)
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to delete post: %v", err)
+	}
+	s.Outbox.Notify()
+	s.invalidateFeedCache(ctx)
+	s.forgetPost(ctx, id)
+
+	if err := s.likeStore().Drop(ctx, id); err != nil {
+		log.Printf("🔴 Failed to drop likes of deleted post %s: %v", id, err)
+	}
+	// Unread notifications may have gone with the post, recount on next read.
+	if s.Redis_DB != nil {
+		if err := s.Redis_DB.Del(ctx, unreadNotificationsKey(currentUserID)).Err(); err != nil {
+			log.Printf("🔴 Failed to reset unread notifications of %s: %v", currentUserID, err)
+		}
+	}
+
+	return nil
+}
+
+// setLike likes, unlikes or toggles the post for the user. The like store
+// decides the new state atomically, then it is persisted; if persisting fails
+// the store is rolled back. If the store doesn't hold the post's likes,
+// Postgres decides on its own.
+//
+// A racing like and unlike may persist in the other order than the store
+// decided them. So what is persisted is the state the store holds at the
+// time, read under the like's lock: whichever request persists last writes
+// the store's final state.
+func (s *Server) setLike(ctx context.Context, userID, postID string, mode LikeMode) (*likedPost, error) {
+	dbPost, err := s.findPost(ctx, postID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "post not found: %s", postID)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch post: %v", err)
+	}
+
+	// Likes can't be decided without the store, and deciding them in
+	// Postgres alone would leave the store behind once it is back.
+	if _, err := s.warmLikes(ctx, []string{postID}); err != nil {
+		if redisDown(err) {
+			return nil, status.Error(codes.Unavailable, "likes are temporarily unavailable, try again later")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to load likes: %v", err)
+	}
+
+	store := s.likeStore()
+	isLiked, totalLikes, changed, err := store.Set(ctx, postID, userID, mode)
+	if errors.Is(err, ErrNotCached) {
+		isLiked, totalLikes, changed, err = s.likeRepo().Apply(ctx, postID, userID, mode)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to %s post: %v", mode, err)
+		}
+		return s.likeChanged(ctx, dbPost, userID, isLiked, totalLikes, changed), nil
+	}
+	if redisDown(err) {
+		return nil, status.Error(codes.Unavailable, "likes are temporarily unavailable, try again later")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to %s post: %v", mode, err)
+	}
+
+	// Only a change is worth an event, retries of the same like are not.
+	var event *blog.FeedEvent
+	if changed {
+		event = &blog.FeedEvent{
+			Type:       blog.FeedEvent_LIKES_CHANGED,
+			PostId:     postID,
+			LikesCount: int32(totalLikes),
+		}
+	}
+
+	current := func() (bool, error) {
+		counts, err := store.Counts(ctx, userID, []string{postID})
+		if err != nil {
+			// Without the store, this request's own result is the best guess.
+			logDegraded("persisting the like as decided", err)
+			return isLiked, nil
+		}
+		count, ok := counts[postID]
+		if !ok {
+			// Dropped from the store meanwhile, e.g. with the post.
+			return isLiked, nil
+		}
+		return count.Liked, nil
+	}
+	if err := s.likeRepo().Save(ctx, postID, userID, current, event); err != nil {
+		if changed {
+			undo := LikeUnset
+			if !isLiked {
+				undo = LikeSet
+			}
+			if _, _, _, undoErr := store.Set(ctx, postID, userID, undo); undoErr != nil {
+				log.Printf("🔴 Failed to revert like of post %s by %s: %v", postID, userID, undoErr)
+			}
+		}
+		return nil, status.Errorf(codes.Internal, "failed to persist like: %v", err)
+	}
+
+	return s.likeChanged(ctx, dbPost, userID, isLiked, totalLikes, changed), nil
+}
+
+// likeChanged follows up on a persisted like and returns the post as the
+// user now sees it.
+func (s *Server) likeChanged(ctx context.Context, dbPost *db.Post, userID string, isLiked bool, totalLikes int64, changed bool) *likedPost {
+	if changed {
+		s.Outbox.Notify()
+		if isLiked {
+			s.notify(ctx, dbPost.AuthorID, userID, notificationPostLiked, dbPost.ID)
+		}
+	}
+
+	return &likedPost{
+		Post:       *dbPost,
+		LikesCount: int32(totalLikes),
+		IsLiked:    isLiked,
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestPostUpdateSetters(t *testing.T) {
+	tests := []struct {
+		paths []string
+		code  codes.Code
+	}{
+		{paths: nil, code: codes.OK},
+		{paths: []string{"body"}, code: codes.OK},
+		{paths: []string{"id"}, code: codes.InvalidArgument},
+		{paths: []string{"author"}, code: codes.InvalidArgument},
+		{paths: []string{"author.nick_name"}, code: codes.InvalidArgument},
+		{paths: []string{"created_at"}, code: codes.InvalidArgument},
+		{paths: []string{"body", "likes_count"}, code: codes.InvalidArgument},
+		{paths: []string{"title"}, code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		setters, err := postUpdateSetters(tt.paths)
+		require.Equal(t, tt.code, status.Code(err), "paths %v", tt.paths)
+		if err != nil {
+			continue
+		}
+
+		post := db.Post{ID: "post-1", Body: "old"}
+		for _, set := range setters {
+			set(&post, &postUpdate{Body: "new"})
+		}
+		require.Equal(t, "new", post.Body)
+		require.Equal(t, "post-1", post.ID)
+	}
+}
+
+func TestV1PostWritesRequireUserID(t *testing.T) {
+	s := &Server{}
+	ctx := context.Background()
+	writes := map[string]func(context.Context) error{
+		"CreatePost": func(ctx context.Context) error {
+			_, err := s.CreatePost(ctx, &blog.CreatePostRequest{Body: "Post"})
+			return err
+		},
+		"UpdatePost": func(ctx context.Context) error {
+			_, err := s.UpdatePost(ctx, &blog.UpdatePostRequest{Id: "post-1", Body: "Post"})
+			return err
+		},
+		"DeletePost": func(ctx context.Context) error {
+			_, err := s.DeletePost(ctx, &blog.DeletePostRequest{Id: "post-1"})
+			return err
+		},
+	}
+	for name, write := range writes {
+		err := write(ctx)
+		require.Equal(t, codes.Unauthenticated, status.Code(err), name)
+		require.Equal(t, "missing user id", status.Convert(err).Message(), name)
+
+		err = write(metadata.NewIncomingContext(ctx, metadata.MD{}))
+		require.Equal(t, "user-id header is required", status.Convert(err).Message(), name)
+	}
+
+	_, err := s.GetPosts(ctx, &blog.GetPostsRequest{})
+	require.Equal(t, "missing metadata", status.Convert(err).Message())
+}
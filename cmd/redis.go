@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis runs as a single node, behind Sentinel or as a Cluster. In a Cluster
+// a Lua script, a MULTI transaction or a WATCH may only touch keys of the
+// same hash slot, so keys used together share a hash tag: the part in braces,
+// e.g. post:{<id>}:likes and post:{<id>}:likers. Plain pipelines may span
+// slots, the client splits them by node.
+
+// Redis deployment modes.
+const (
+	RedisSingle   = "single"
+	RedisSentinel = "sentinel"
+	RedisCluster  = "cluster"
+)
+
+type RedisConfig struct {
+	Mode string
+	// Addrs is the node address in single mode, the sentinel addresses in
+	// sentinel mode and the seed nodes in cluster mode.
+	Addrs      []string
+	MasterName string
+	Password   string
+	DB         int
+}
+
+// NewRedisClient returns a client for the configured deployment.
+func NewRedisClient(cfg RedisConfig) (redis.UniversalClient, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, fmt.Errorf("no redis address")
+	}
+
+	switch cfg.Mode {
+	case RedisSingle, "":
+		if len(cfg.Addrs) > 1 {
+			return nil, fmt.Errorf("single redis takes one address, got %d", len(cfg.Addrs))
+		}
+		return redis.NewClient(&redis.Options{
+			Addr:     cfg.Addrs[0],
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}), nil
+	case RedisSentinel:
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("sentinel redis needs a master name")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    cfg.MasterName,
+			SentinelAddrs: cfg.Addrs,
+			Password:      cfg.Password,
+			DB:            cfg.DB,
+		}), nil
+	case RedisCluster:
+		if cfg.DB != 0 {
+			return nil, fmt.Errorf("cluster redis only has database 0")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    cfg.Addrs,
+			Password: cfg.Password,
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown redis mode %q, want %s, %s or %s", cfg.Mode, RedisSingle, RedisSentinel, RedisCluster)
+}
+
+// errStopScan ends a scanKeys early when returned by fn.
+var errStopScan = errors.New("stop scan")
+
+// scanKeys calls fn with every key matching pattern. In a cluster every
+// master is scanned, a plain SCAN only covers the node it is sent to.
+func scanKeys(ctx context.Context, rdb redis.UniversalClient, pattern string, fn func(key string) error) error {
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			if err := fn(iter.Val()); err != nil {
+				return err
+			}
+		}
+		return iter.Err()
+	}
+
+	if cluster, ok := rdb.(*redis.ClusterClient); ok {
+		// Masters are scanned concurrently, fn isn't expected to cope.
+		var mu sync.Mutex
+		serial := fn
+		fn = func(key string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return serial(key)
+		}
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+	}
+	return scan(ctx, rdb)
+}
@@ -2,46 +2,369 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"go_grpc_blog/db"
+	"log"
+	"strconv"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func UpdateCache(s *Server, ctx context.Context) error {
-	var dbPosts []db.Post
-	result := s.Sql_DB.Preload("Author").Order("created_at desc").Limit(10).Offset(0).Find(&dbPosts)
-	if result.Error != nil {
-		return status.Errorf(codes.Internal, "failed to fetch posts: %v", result.Error)
+// The feed cache holds a window of the newest posts that every page within it
+// is sliced from; deeper pages are read from Postgres. The key carries the
+// format version, bump it whenever the cached payload changes shape so that
+// replicas never decode an old format.
+//
+// The key also carries a generation shared by all replicas. Creating, updating
+// or deleting a post increments it, which moves every replica to a new, empty
+// key at once; windows of older generations are never read again and expire.
+// A window is always stored under the generation read before loading it, so a
+// window loaded before a change can't be served after it.
+//
+// On a miss only one request per replica rebuilds the window, and only the
+// replica holding the posts_cache:{v2}:lock lease loads it from Postgres. If
+// the window merely expired, the others serve the last stored window
+// (posts_cache:{v2}:stale) meanwhile. After a post change they wait a moment
+// for the new one instead: the stale window was stored for an older
+// generation, posts_cache:{v2}:stale-generation, and misses the change.
+//
+// The feed cache keys share a hash tag so that they can be written together
+// in a Redis Cluster.
+const (
+	feedCacheVersion       = "v2"
+	feedCachePrefix        = "posts_cache:{" + feedCacheVersion + "}:"
+	feedCacheGenerationKey = feedCachePrefix + "generation"
+	feedCacheStaleKey      = feedCachePrefix + "stale"
+	feedCacheStaleGenKey   = feedCachePrefix + "stale-generation"
+	feedCacheLockKey       = feedCachePrefix + "lock"
+	feedCacheWindow        = 200
+	feedCacheTTL           = 2 * time.Minute
+	feedCacheStaleTTL      = 10 * time.Minute
+	feedCacheLockTTL       = 10 * time.Second
+
+	// How long a request waits for another replica's rebuild before
+	// reading its page from Postgres.
+	feedRebuildWait     = 500 * time.Millisecond
+	feedRebuildWaitPoll = 50 * time.Millisecond
+)
+
+// releaseLockScript deletes the lock only if it is still ours, it may have
+// expired and been taken by someone else.
+//
+//	KEYS[1] lock   ARGV[1] token
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func feedCacheKey(generation int64) string {
+	return feedCachePrefix + strconv.FormatInt(generation, 10)
+}
+
+// RedisFeedCache shares the feed window between replicas through Redis.
+type RedisFeedCache struct {
+	rdb   redis.UniversalClient
+	Codec CacheCodec
+}
+
+func NewRedisFeedCache(rdb redis.UniversalClient) *RedisFeedCache {
+	return &RedisFeedCache{rdb: rdb}
+}
+
+func (c *RedisFeedCache) Generation(ctx context.Context) (int64, error) {
+	generation, err := c.rdb.Get(ctx, feedCacheGenerationKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return generation, err
+}
+
+func (c *RedisFeedCache) Window(ctx context.Context, generation int64) ([]db.Post, error) {
+	val, err := c.rdb.Get(ctx, feedCacheKey(generation)).Bytes()
+	countLookup("feed", "redis", err == nil)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	postsJSON, err := json.Marshal(dbPosts)
+	posts, err := decodeCachedPosts(val)
 	if err != nil {
-		return fmt.Errorf("failed to marshal posts: %v", err)
+		return nil, fmt.Errorf("failed to decode posts: %w", err)
 	}
+	return posts, nil
+}
 
-	err = s.Redis_DB.Set(ctx, "posts_cache", postsJSON, 2*time.Minute).Err()
+func (c *RedisFeedCache) Store(ctx context.Context, generation int64, posts []db.Post) error {
+	if posts == nil {
+		posts = []db.Post{}
+	}
+	payload, err := c.Codec.EncodePosts(posts)
 	if err != nil {
-		return fmt.Errorf("failed to set cache: %v", err)
+		return fmt.Errorf("failed to encode posts: %v", err)
+	}
+
+	pipe := c.rdb.TxPipeline()
+	pipe.Set(ctx, feedCacheKey(generation), payload, feedCacheTTL)
+	pipe.Set(ctx, feedCacheStaleKey, payload, feedCacheStaleTTL)
+	pipe.Set(ctx, feedCacheStaleGenKey, generation, feedCacheStaleTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisFeedCache) Invalidate(ctx context.Context) error {
+	return c.rdb.Incr(ctx, feedCacheGenerationKey).Err()
+}
+
+func (c *RedisFeedCache) Stale(ctx context.Context) ([]db.Post, int64, error) {
+	pipe := c.rdb.Pipeline()
+	valCmd := pipe.Get(ctx, feedCacheStaleKey)
+	generationCmd := pipe.Get(ctx, feedCacheStaleGenKey)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+	val, err := valCmd.Bytes()
+	if err == redis.Nil {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	// Windows stored before the generation was kept with them are only
+	// known to be stale, tell the caller so with a generation of -1.
+	generation, err := generationCmd.Int64()
+	if err == redis.Nil {
+		generation = -1
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	posts, err := decodeCachedPosts(val)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode posts: %w", err)
+	}
+	return posts, generation, nil
+}
+
+func (c *RedisFeedCache) TryLock(ctx context.Context, ttl time.Duration) (func(), bool, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, false, err
+	}
+
+	ok, err := c.rdb.SetNX(ctx, feedCacheLockKey, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	unlock := func() {
+		// The caller's context may be done by now.
+		err := releaseLockScript.Run(context.Background(), c.rdb, []string{feedCacheLockKey}, token).Err()
+		if err != nil {
+			log.Printf("🔴 Failed to release feed cache lock: %v", err)
+		}
+	}
+	return unlock, true, nil
+}
+
+// InvalidateCache makes every replica stop serving the cached feed.
+func InvalidateCache(s *Server, ctx context.Context) error {
+	if err := s.feedCache().Invalidate(ctx); err != nil {
+		return fmt.Errorf("failed to invalidate cache: %v", err)
+	}
+	return nil
+}
+
+// InvalidatePosts drops what is cached about posts that were written behind
+// the server's back, e.g. by seeding: their likes are re-read from Postgres
+// on first use and the feed is invalidated.
+func InvalidatePosts(s *Server, ctx context.Context, postIDs []string) error {
+	for _, postID := range postIDs {
+		if err := s.likeStore().Drop(ctx, postID); err != nil {
+			return fmt.Errorf("failed to drop cached likes of post %s: %v", postID, err)
+		}
 	}
+	return InvalidateCache(s, ctx)
+}
+
+// invalidateFeedCache runs after a post change has been committed. If it fails
+// the feed is stale until the next refresh.
+func (s *Server) invalidateFeedCache(ctx context.Context) {
+	if err := InvalidateCache(s, ctx); err != nil {
+		log.Printf("🔴 %v", err)
+	}
+}
+
+// loadFeedWindow reads the newest feedCacheWindow posts from Postgres.
+func loadFeedWindow(s *Server, ctx context.Context) ([]db.Post, error) {
+	dbPosts, err := s.postRepo().Newest(ctx, 0, feedCacheWindow)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch posts: %v", err)
+	}
+	return dbPosts, nil
+}
+
+// UpdateCache refreshes the cached feed. Post changes invalidate the cache
+// right away, so this is only a safety net. Replicas don't refresh at the
+// same time: whoever holds the rebuild lease does it and the rest skip.
+func UpdateCache(s *Server, ctx context.Context) error {
+	cache := s.feedCache()
+	unlock, ok, err := cache.TryLock(ctx, feedCacheLockTTL)
+	if err != nil {
+		return fmt.Errorf("failed to lock cache: %v", err)
+	}
+	if !ok {
+		return nil
+	}
+	defer unlock()
 
+	generation, err := cache.Generation(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get cache generation: %v", err)
+	}
+	dbPosts, err := loadFeedWindow(s, ctx)
+	if err != nil {
+		return err
+	}
+	if err := cache.Store(ctx, generation, dbPosts); err != nil {
+		return fmt.Errorf("failed to set cache: %v", err)
+	}
 	return nil
 }
 
+// GetCachedPosts returns the cached window of newest posts, or nil if it is
+// not cached, together with the current generation.
+func GetCachedPosts(s *Server, ctx context.Context) ([]db.Post, int64, error) {
+	cache := s.feedCache()
+	generation, err := cache.Generation(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get cache generation: %w", err)
+	}
+
+	posts, err := cache.Window(ctx, generation)
+	if err != nil {
+		return nil, generation, fmt.Errorf("failed to get cache: %w", err)
+	}
+	return posts, generation, nil
+}
+
+// cachedPage serves a page from the cached window, filling the cache on a
+// miss. ok is false if the page has to be read from Postgres instead.
+func cachedPage(s *Server, ctx context.Context, start, stop int64) (page []db.Post, ok bool, err error) {
+	if stop >= feedCacheWindow {
+		return nil, false, nil
+	}
+
+	window, generation, err := GetCachedPosts(s, ctx)
+	if errors.Is(err, ErrNotCached) {
+		return nil, false, nil
+	}
+	if redisDown(err) {
+		logDegraded("serving the feed from Postgres", err)
+		return nil, false, nil
+	}
+	if err != nil {
+		log.Printf("🔴 Failed to load feed cache: %v", err)
+		return nil, false, nil
+	}
+	if window == nil {
+		window, err = s.rebuildFeedWindow(ctx, generation)
+		if err != nil {
+			return nil, false, err
+		}
+		if window == nil {
+			return nil, false, nil
+		}
+	}
+
+	// A window shorter than feedCacheWindow holds every post there is.
+	if start >= int64(len(window)) {
+		return []db.Post{}, true, nil
+	}
+	return window[start:min(stop+1, int64(len(window)))], true, nil
+}
+
+// rebuildFeedWindow handles a miss of the window of generation. Concurrent
+// misses share one rebuild. If the window of the same generation expired or
+// is being refreshed, the stale copy is returned right away and the rebuild
+// finishes in the background; after a post change moved to a new generation
+// the stale copy lacks it, so the rebuild is waited for. It returns nil if
+// the window couldn't be had in time.
+func (s *Server) rebuildFeedWindow(ctx context.Context, generation int64) ([]db.Post, error) {
+	key := strconv.FormatInt(generation, 10)
+	// The rebuild outlives a caller that gives up on it.
+	fill := s.feedFills.DoChan(key, func() (any, error) {
+		return s.fillFeedWindow(context.WithoutCancel(ctx), generation)
+	})
+
+	stale, staleGeneration, err := s.feedCache().Stale(ctx)
+	if err == nil && stale != nil && staleGeneration == generation {
+		return stale, nil
+	}
+
+	select {
+	case res := <-fill:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		window, _ := res.Val.([]db.Post)
+		return window, nil
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
 
-func GetCachedPosts(s *Server, ctx context.Context) ([]db.Post, error) {
-	val, err := s.Redis_DB.Get(ctx, "posts_cache").Result()
+// fillFeedWindow loads the window from Postgres if this replica gets the
+// rebuild lease, or waits for the replica that has it.
+func (s *Server) fillFeedWindow(ctx context.Context, generation int64) ([]db.Post, error) {
+	cache := s.feedCache()
+	unlock, ok, err := cache.TryLock(ctx, feedCacheLockTTL)
 	if err != nil {
+		// Better to rebuild twice than to not serve the feed.
+		log.Printf("🔴 Failed to lock feed cache: %v", err)
+		unlock, ok = func() {}, true
+	}
+
+	if !ok {
+		for waited := time.Duration(0); waited < feedRebuildWait; waited += feedRebuildWaitPoll {
+			time.Sleep(feedRebuildWaitPoll)
+			window, err := cache.Window(ctx, generation)
+			if redisDown(err) {
+				logDegraded("serving the feed from Postgres", err)
+				return nil, nil
+			}
+			if err != nil {
+				// The page is read from Postgres instead.
+				log.Printf("🔴 Failed to read rebuilt feed cache: %v", err)
+				return nil, nil
+			}
+			if window != nil {
+				return window, nil
+			}
+		}
 		return nil, nil
 	}
+	defer unlock()
 
-	var posts []db.Post
-	if err := json.Unmarshal([]byte(val), &posts); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal posts: %v", err)
+	// The previous holder may have just finished.
+	if window, err := cache.Window(ctx, generation); err == nil && window != nil {
+		return window, nil
 	}
 
-	return posts, nil
+	window, err := loadFeedWindow(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Store(ctx, generation, window); err != nil {
+		log.Printf("🔴 Failed to fill feed cache: %v", err)
+	}
+	return window, nil
 }
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedPageSlicesWindow(t *testing.T) {
+	ctx := context.Background()
+	s := &Server{Redis_DB: newTestRedis(t)}
+
+	window := make([]db.Post, 15)
+	for i := range window {
+		window[i] = db.Post{ID: fmt.Sprintf("post-%d", i)}
+	}
+	postsJSON, err := json.Marshal(window)
+	require.NoError(t, err)
+	require.NoError(t, s.Redis_DB.Set(ctx, feedCacheKey(0), postsJSON, 0).Err())
+
+	page, ok, err := cachedPage(s, ctx, 0, 9)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, page, 10)
+	require.Equal(t, "post-0", page[0].ID)
+
+	page, ok, err = cachedPage(s, ctx, 10, 19)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, page, 5)
+	require.Equal(t, "post-10", page[0].ID)
+
+	page, ok, err = cachedPage(s, ctx, 20, 29)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, page)
+
+	// Pages reaching past the window are left to Postgres.
+	_, ok, err = cachedPage(s, ctx, feedCacheWindow-5, feedCacheWindow+4)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestInvalidateCacheMovesToNewGeneration(t *testing.T) {
+	ctx := context.Background()
+	s := &Server{Redis_DB: newTestRedis(t)}
+
+	postsJSON, err := json.Marshal([]db.Post{{ID: "post-1"}})
+	require.NoError(t, err)
+	require.NoError(t, s.Redis_DB.Set(ctx, feedCacheKey(0), postsJSON, 0).Err())
+
+	window, generation, err := GetCachedPosts(s, ctx)
+	require.NoError(t, err)
+	require.Len(t, window, 1)
+	require.Zero(t, generation)
+
+	require.NoError(t, InvalidateCache(s, ctx))
+
+	window, generation, err = GetCachedPosts(s, ctx)
+	require.NoError(t, err)
+	require.Nil(t, window)
+	require.Equal(t, int64(1), generation)
+}
+
+func TestCachedPageRebuildsOnceForConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	users := NewMemoryUserRepository()
+	posts := &slowPostRepository{PostRepository: NewMemoryPostRepository(users), delay: 100 * time.Millisecond}
+	s := &Server{Posts: posts, Users: users, Cache: NewMemoryFeedCache()}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := cachedPage(s, ctx, 0, 9)
+			if err == nil && !ok {
+				err = fmt.Errorf("page not served from cache")
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 1, posts.newest.Load())
+}
+
+func TestCachedPageServesStaleWhileRebuilding(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	users := NewMemoryUserRepository()
+	posts := &slowPostRepository{PostRepository: NewMemoryPostRepository(users), delay: 50 * time.Millisecond}
+	cache := NewRedisFeedCache(rdb)
+	s := &Server{Posts: posts, Users: users, Cache: cache}
+
+	// The window expired: the stale copy is as good as it was.
+	require.NoError(t, cache.Store(ctx, 0, []db.Post{{ID: "post-1"}}))
+	mr.FastForward(feedCacheTTL + time.Second)
+
+	page, ok, err := cachedPage(s, ctx, 0, 9)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"post-1"}, postIDs(page))
+
+	require.Eventually(t, func() bool {
+		window, _ := cache.Window(ctx, 0)
+		return window != nil
+	}, time.Second, 10*time.Millisecond)
+	require.EqualValues(t, 1, posts.newest.Load())
+}
+
+func TestCachedPageWaitsForRebuildAfterWrite(t *testing.T) {
+	ctx := context.Background()
+	users := NewMemoryUserRepository()
+	repo := NewMemoryPostRepository(users)
+	posts := &slowPostRepository{PostRepository: repo, delay: 50 * time.Millisecond}
+	cache := NewMemoryFeedCache()
+	s := &Server{Posts: posts, Users: users, Cache: cache}
+
+	require.NoError(t, cache.Store(ctx, 0, []db.Post{}))
+	require.NoError(t, repo.Create(ctx, &db.Post{ID: "post-1", Body: "Post by Naruto!"}, nil))
+	require.NoError(t, cache.Invalidate(ctx))
+
+	// The stale window misses the new post, the writer must see it.
+	page, ok, err := cachedPage(s, ctx, 0, 9)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"post-1"}, postIDs(page))
+}
+
+// lockedFailingCache is a feed cache whose rebuild lease is always held by
+// another replica and whose windows can't be read.
+type lockedFailingCache struct {
+	*MemoryFeedCache
+}
+
+func (lockedFailingCache) TryLock(context.Context, time.Duration) (func(), bool, error) {
+	return nil, false, nil
+}
+
+func (lockedFailingCache) Window(context.Context, int64) ([]db.Post, error) {
+	return nil, errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+}
+
+func TestFillFeedWindowLogsFailedReads(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	s := &Server{Cache: lockedFailingCache{NewMemoryFeedCache()}}
+	window, err := s.fillFeedWindow(context.Background(), 0)
+	require.NoError(t, err)
+	require.Nil(t, window)
+	require.Contains(t, logged.String(), "WRONGTYPE")
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRedisClient(t *testing.T) {
+	rdb, err := NewRedisClient(RedisConfig{Addrs: []string{"127.0.0.1:6379"}})
+	require.NoError(t, err)
+	require.IsType(t, &redis.Client{}, rdb)
+
+	rdb, err = NewRedisClient(RedisConfig{Mode: RedisSentinel, Addrs: []string{"10.0.0.1:26379", "10.0.0.2:26379"}, MasterName: "blog"})
+	require.NoError(t, err)
+	require.IsType(t, &redis.Client{}, rdb)
+
+	rdb, err = NewRedisClient(RedisConfig{Mode: RedisCluster, Addrs: []string{"10.0.0.1:6379", "10.0.0.2:6379"}})
+	require.NoError(t, err)
+	require.IsType(t, &redis.ClusterClient{}, rdb)
+
+	for _, cfg := range []RedisConfig{
+		{},
+		{Mode: RedisSingle, Addrs: []string{"a:6379", "b:6379"}},
+		{Mode: RedisSentinel, Addrs: []string{"a:26379"}},
+		{Mode: RedisCluster, Addrs: []string{"a:6379"}, DB: 1},
+		{Mode: "replicated", Addrs: []string{"a:6379"}},
+	} {
+		_, err := NewRedisClient(cfg)
+		require.Error(t, err, "%+v", cfg)
+	}
+}
+
+// hashTag returns the part of key Redis Cluster hashes to pick its slot.
+func hashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+func TestKeysUsedTogetherShareASlot(t *testing.T) {
+	require.Equal(t, hashTag(postLikesKey("post-1")), hashTag(postLikersKey("post-1")))
+	require.NotEqual(t, hashTag(postLikesKey("post-1")), hashTag(postLikesKey("post-2")))
+
+	require.Equal(t, hashTag(feedCacheKey(7)), hashTag(feedCacheStaleKey))
+	require.Equal(t, hashTag(feedCacheKey(7)), hashTag(feedCacheStaleGenKey))
+	require.Equal(t, hashTag(feedCacheKey(7)), hashTag(feedCacheGenerationKey))
+	require.NotEqual(t, hashTag(feedCacheKey(7)), hashTag(postCacheKey("post-1")))
+	require.NotEqual(t, hashTag(postCacheKey("post-1")), hashTag(postCacheKey("post-2")))
+}
+
+func TestMigrateLikeKeys(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+
+	rdb.HSet(ctx, "post:post-1:likes", "total-likes", 1, "user-1", true)
+	rdb.ZAdd(ctx, "post:post-1:likers", &redis.Z{Score: 1, Member: "user-1"})
+	rdb.ZAdd(ctx, "user:user-1:liked", &redis.Z{Score: 1, Member: "post-1"})
+	// Already written under the new name, which wins.
+	rdb.HSet(ctx, "post:post-2:likes", "total-likes", 0)
+	rdb.HSet(ctx, postLikesKey("post-2"), "total-likes", 1, "user-2", true)
+	rdb.Set(ctx, "user:user-1:notifications:unread", 3, 0)
+
+	require.NoError(t, MigrateLikeKeys(ctx, rdb))
+
+	keys, err := rdb.Keys(ctx, "*").Result()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{
+		postLikesKey("post-1"), postLikersKey("post-1"), userLikedKey("user-1"),
+		postLikesKey("post-2"), "user:user-1:notifications:unread", likeKeysMigrationKey,
+	}, keys)
+
+	total, err := rdb.HGet(ctx, postLikesKey("post-2"), "total-likes").Int()
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+}
+
+func TestMigrateLikeKeysConcurrently(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+	for i := 0; i < 100; i++ {
+		rdb.HSet(ctx, fmt.Sprintf("post:post-%d:likes", i), "total-likes", 1, "user-1", true)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = MigrateLikeKeys(ctx, rdb)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	keys, err := rdb.Keys(ctx, "post:*:likes").Result()
+	require.NoError(t, err)
+	require.Len(t, keys, 100)
+	for _, key := range keys {
+		require.Contains(t, key, "{")
+	}
+
+	// What a replica that lost the race gets for a key renamed under it.
+	require.True(t, isNoSuchKey(rdb.RenameNX(ctx, "post:post-0:likes", postLikesKey("post-0")).Err()))
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+)
+
+// Handlers read and write posts, users, likes and notifications through
+// repositories rather than gorm, so they can run against Postgres or entirely
+// in memory. Webhooks and the outbox relay still use Sql_DB directly.
+
+// ErrNotFound is returned by repositories for an id that doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// PostRepository stores posts. Posts are always returned with their Author.
+type PostRepository interface {
+	// Get returns the post, or ErrNotFound.
+	Get(ctx context.Context, id string) (*db.Post, error)
+	// GetMany returns the posts among ids that exist, in no particular
+	// order.
+	GetMany(ctx context.Context, ids []string) ([]db.Post, error)
+	Exists(ctx context.Context, id string) (bool, error)
+	// Newest returns up to limit posts, newest first, skipping offset.
+	Newest(ctx context.Context, offset, limit int) ([]db.Post, error)
+
+	// Create, Update and Delete record event, if not nil, in the outbox in
+	// the same transaction as the change.
+	Create(ctx context.Context, post *db.Post, event *blog.FeedEvent) error
+	// Update saves every field of a post read with Get.
+	Update(ctx context.Context, post *db.Post, event *blog.FeedEvent) error
+	// Delete removes the post together with its likes and notifications, or
+	// returns ErrNotFound.
+	Delete(ctx context.Context, id string, event *blog.FeedEvent) error
+}
+
+// UserRepository stores users.
+type UserRepository interface {
+	// Get returns the user, or ErrNotFound.
+	Get(ctx context.Context, id string) (*db.User, error)
+	// GetMany returns the users among ids that exist, in no particular
+	// order.
+	GetMany(ctx context.Context, ids []string) ([]db.User, error)
+	Create(ctx context.Context, user *db.User) error
+}
+
+// LikeRepository stores likes, the durable record behind the LikeStore cache.
+type LikeRepository interface {
+	// ForPosts returns the likes of the given posts.
+	ForPosts(ctx context.Context, postIDs []string) ([]db.Like, error)
+	// Likers returns up to limit users who liked the post, newest like
+	// first, skipping offset.
+	Likers(ctx context.Context, postID string, offset, limit int) ([]string, error)
+	// Liked returns up to limit posts the user liked, newest like first,
+	// skipping offset.
+	Liked(ctx context.Context, userID string, offset, limit int) ([]string, error)
+
+	// Save stores whether the user likes the post as state reports it, and
+	// records event, if not nil, in the outbox in the same transaction. state
+	// is called while holding a lock on the user's like of the post, so that
+	// of concurrent saves the last one stores the latest state.
+	Save(ctx context.Context, postID, userID string, state func() (liked bool, err error), event *blog.FeedEvent) error
+	// Apply applies mode to the user's like on its own, for when the like
+	// store doesn't hold the post's likes. Like LikeStore.Set it only
+	// reports a change made by this call, and records a LIKES_CHANGED event
+	// for it.
+	Apply(ctx context.Context, postID, userID string, mode LikeMode) (liked bool, total int64, changed bool, err error)
+
+	// Count returns the number of likes stored.
+	Count(ctx context.Context) (int64, error)
+	// Import stores the likes that aren't stored yet and returns how many
+	// there were.
+	Import(ctx context.Context, likes []db.Like) (int64, error)
+	// Each calls fn with every like, ordered by post.
+	Each(ctx context.Context, fn func(like db.Like) error) error
+}
+
+// NotificationRepository stores notifications. Notifications are always
+// returned with their Actor.
+type NotificationRepository interface {
+	// Add records n.ActorID doing n.Kind on n.RecipientID's post n.PostID.
+	// It is folded into the recipient's unread notification for the post if
+	// there is one, n is only stored otherwise. Add reports whether n was
+	// stored.
+	Add(ctx context.Context, n *db.Notification) (bool, error)
+	// List returns up to limit of the user's notifications, most recently
+	// updated first, skipping offset.
+	List(ctx context.Context, userID string, unreadOnly bool, offset, limit int) ([]db.Notification, error)
+	// Get returns the user's notification, or ErrNotFound.
+	Get(ctx context.Context, id, userID string) (*db.Notification, error)
+	// MarkRead marks the user's notification read and reports whether it was
+	// unread.
+	MarkRead(ctx context.Context, id, userID string) (bool, error)
+	// MarkAllRead marks the user's notifications read and returns how many
+	// were unread.
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+	CountUnread(ctx context.Context, userID string) (int64, error)
+}
+
+// postRepo returns the configured post repository, by default the gorm one
+// on Sql_DB.
+func (s *Server) postRepo() PostRepository {
+	if s.Posts != nil {
+		return s.Posts
+	}
+	return NewGormPostRepository(s.Sql_DB)
+}
+
+// userRepo returns the configured user repository, defaulting like postRepo.
+func (s *Server) userRepo() UserRepository {
+	if s.Users != nil {
+		return s.Users
+	}
+	return NewGormUserRepository(s.Sql_DB)
+}
+
+// likeRepo returns the configured like repository, defaulting like postRepo.
+func (s *Server) likeRepo() LikeRepository {
+	if s.LikeRepo != nil {
+		return s.LikeRepo
+	}
+	return NewGormLikeRepository(s.Sql_DB)
+}
+
+// notificationRepo returns the configured notification repository,
+// defaulting like postRepo.
+func (s *Server) notificationRepo() NotificationRepository {
+	if s.Notifications != nil {
+		return s.Notifications
+	}
+	return NewGormNotificationRepository(s.Sql_DB)
+}
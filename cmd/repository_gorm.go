@@ -0,0 +1,382 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormPostRepository stores posts in SQL.
+type GormPostRepository struct {
+	sqlDB *gorm.DB
+}
+
+func NewGormPostRepository(sqlDB *gorm.DB) *GormPostRepository {
+	return &GormPostRepository{sqlDB: sqlDB}
+}
+
+func (r *GormPostRepository) Get(ctx context.Context, id string) (*db.Post, error) {
+	var post db.Post
+	err := r.sqlDB.WithContext(ctx).Preload("Author").First(&post, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+func (r *GormPostRepository) GetMany(ctx context.Context, ids []string) ([]db.Post, error) {
+	posts := []db.Post{}
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	err := r.sqlDB.WithContext(ctx).Preload("Author").Where("id IN ?", ids).Find(&posts).Error
+	return posts, err
+}
+
+func (r *GormPostRepository) Exists(ctx context.Context, id string) (bool, error) {
+	var count int64
+	err := r.sqlDB.WithContext(ctx).Model(&db.Post{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormPostRepository) Newest(ctx context.Context, offset, limit int) ([]db.Post, error) {
+	posts := []db.Post{}
+	err := r.sqlDB.WithContext(ctx).Preload("Author").Order("created_at desc, id desc").Limit(limit).Offset(offset).Find(&posts).Error
+	return posts, err
+}
+
+func (r *GormPostRepository) Create(ctx context.Context, post *db.Post, event *blog.FeedEvent) error {
+	return r.sqlDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The author already exists, don't upsert it along with the post.
+		if err := tx.Omit(clause.Associations).Create(post).Error; err != nil {
+			return err
+		}
+		return writeOutboxEvent(tx, event)
+	})
+}
+
+func (r *GormPostRepository) Update(ctx context.Context, post *db.Post, event *blog.FeedEvent) error {
+	return r.sqlDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(post).Error; err != nil {
+			return err
+		}
+		return writeOutboxEvent(tx, event)
+	})
+}
+
+func (r *GormPostRepository) Delete(ctx context.Context, id string, event *blog.FeedEvent) error {
+	return r.sqlDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id = ?", id).Delete(&db.Like{}).Error; err != nil {
+			return err
+		}
+		if err := dropPostNotifications(tx, id); err != nil {
+			return err
+		}
+		result := tx.Delete(&db.Post{ID: id})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return writeOutboxEvent(tx, event)
+	})
+}
+
+func writeOutboxEvent(tx *gorm.DB, event *blog.FeedEvent) error {
+	if event == nil {
+		return nil
+	}
+	return writeOutbox(tx, event)
+}
+
+// GormUserRepository stores users in SQL.
+type GormUserRepository struct {
+	sqlDB *gorm.DB
+}
+
+func NewGormUserRepository(sqlDB *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{sqlDB: sqlDB}
+}
+
+func (r *GormUserRepository) Get(ctx context.Context, id string) (*db.User, error) {
+	var user db.User
+	err := r.sqlDB.WithContext(ctx).First(&user, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) GetMany(ctx context.Context, ids []string) ([]db.User, error) {
+	users := []db.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.sqlDB.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+func (r *GormUserRepository) Create(ctx context.Context, user *db.User) error {
+	return r.sqlDB.WithContext(ctx).Create(user).Error
+}
+
+// GormLikeRepository stores likes in SQL.
+type GormLikeRepository struct {
+	sqlDB *gorm.DB
+}
+
+func NewGormLikeRepository(sqlDB *gorm.DB) *GormLikeRepository {
+	return &GormLikeRepository{sqlDB: sqlDB}
+}
+
+func (r *GormLikeRepository) ForPosts(ctx context.Context, postIDs []string) ([]db.Like, error) {
+	likes := []db.Like{}
+	if len(postIDs) == 0 {
+		return likes, nil
+	}
+	err := r.sqlDB.WithContext(ctx).Where("post_id IN ?", postIDs).Find(&likes).Error
+	return likes, err
+}
+
+func (r *GormLikeRepository) Likers(ctx context.Context, postID string, offset, limit int) ([]string, error) {
+	userIDs := []string{}
+	err := r.sqlDB.WithContext(ctx).Model(&db.Like{}).
+		Where("post_id = ?", postID).
+		Order("created_at desc, user_id desc").
+		Offset(offset).Limit(limit).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+func (r *GormLikeRepository) Liked(ctx context.Context, userID string, offset, limit int) ([]string, error) {
+	postIDs := []string{}
+	err := r.sqlDB.WithContext(ctx).Model(&db.Like{}).
+		Where("user_id = ?", userID).
+		Order("created_at desc, post_id desc").
+		Offset(offset).Limit(limit).
+		Pluck("post_id", &postIDs).Error
+	return postIDs, err
+}
+
+func (r *GormLikeRepository) Save(ctx context.Context, postID, userID string, state func() (bool, error), event *blog.FeedEvent) error {
+	return r.sqlDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockLike(tx, postID, userID); err != nil {
+			return err
+		}
+		liked, err := state()
+		if err != nil {
+			return err
+		}
+
+		if liked {
+			like := db.Like{PostID: postID, UserID: userID, CreatedAt: time.Now()}
+			err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&like).Error
+		} else {
+			err = tx.Where("post_id = ? AND user_id = ?", postID, userID).Delete(&db.Like{}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return writeOutboxEvent(tx, event)
+	})
+}
+
+// lockLike makes tx hold a lock on the user's like of the post until it ends,
+// whether or not the like exists. Without advisory locks it locks the post
+// instead, which holds up the post's other likes too; without row locks the
+// transaction already holds the database's only write lock.
+func lockLike(tx *gorm.DB, postID, userID string) error {
+	dialect := db.DialectOf(tx)
+	switch {
+	case dialect.AdvisoryLocks:
+		return tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", "like:"+postID+":"+userID).Error
+	case dialect.RowLocks:
+		return lockForUpdate(tx).Select("id").Where("id = ?", postID).Find(&[]db.Post{}).Error
+	}
+	return nil
+}
+
+func (r *GormLikeRepository) Apply(ctx context.Context, postID, userID string, mode LikeMode) (liked bool, total int64, changed bool, err error) {
+	err = r.sqlDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		liked = mode == LikeSet
+		if mode == LikeToggle {
+			var count int64
+			if err := tx.Model(&db.Like{}).Where("post_id = ? AND user_id = ?", postID, userID).Count(&count).Error; err != nil {
+				return err
+			}
+			liked = count == 0
+		}
+
+		var result *gorm.DB
+		if liked {
+			like := db.Like{PostID: postID, UserID: userID, CreatedAt: time.Now()}
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&like)
+		} else {
+			result = tx.Where("post_id = ? AND user_id = ?", postID, userID).Delete(&db.Like{})
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		changed = result.RowsAffected > 0
+
+		if err := tx.Model(&db.Like{}).Where("post_id = ?", postID).Count(&total).Error; err != nil {
+			return err
+		}
+		if !changed {
+			return nil
+		}
+		return writeOutbox(tx, &blog.FeedEvent{
+			Type:       blog.FeedEvent_LIKES_CHANGED,
+			PostId:     postID,
+			LikesCount: int32(total),
+		})
+	})
+	return liked, total, changed, err
+}
+
+func (r *GormLikeRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.sqlDB.WithContext(ctx).Model(&db.Like{}).Count(&count).Error
+	return count, err
+}
+
+func (r *GormLikeRepository) Import(ctx context.Context, likes []db.Like) (int64, error) {
+	if len(likes) == 0 {
+		return 0, nil
+	}
+	result := r.sqlDB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(likes, likesSyncBatchSize)
+	return result.RowsAffected, result.Error
+}
+
+func (r *GormLikeRepository) Each(ctx context.Context, fn func(like db.Like) error) error {
+	rows, err := r.sqlDB.WithContext(ctx).Model(&db.Like{}).Order("post_id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var like db.Like
+		if err := r.sqlDB.ScanRows(rows, &like); err != nil {
+			return err
+		}
+		if err := fn(like); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GormNotificationRepository stores notifications in SQL.
+type GormNotificationRepository struct {
+	sqlDB *gorm.DB
+}
+
+func NewGormNotificationRepository(sqlDB *gorm.DB) *GormNotificationRepository {
+	return &GormNotificationRepository{sqlDB: sqlDB}
+}
+
+func (r *GormNotificationRepository) Add(ctx context.Context, n *db.Notification) (bool, error) {
+	created := false
+	err := r.sqlDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var unread db.Notification
+		// read is reserved in MySQL, map conditions get the columns quoted.
+		where := map[string]any{"recipient_id": n.RecipientID, "kind": n.Kind, "post_id": n.PostID, "read": false}
+		err := lockForUpdate(tx).Where(where).First(&unread).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// There's no row to lock yet: concurrent activity may be creating
+			// the notification too, and the unread index lets only one in.
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations).Create(n)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				created = true
+				return tx.Create(&db.NotificationActor{NotificationID: n.ID, ActorID: n.ActorID}).Error
+			}
+			err = lockForUpdate(tx).Where(where).First(&unread).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&db.NotificationActor{NotificationID: unread.ID, ActorID: n.ActorID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Already counted, e.g. a like after an unlike.
+			return nil
+		}
+		return tx.Model(&unread).Updates(map[string]any{
+			"actor_id":    n.ActorID,
+			"actor_count": gorm.Expr("actor_count + 1"),
+			"updated_at":  n.UpdatedAt,
+		}).Error
+	})
+	return created, err
+}
+
+func (r *GormNotificationRepository) List(ctx context.Context, userID string, unreadOnly bool, offset, limit int) ([]db.Notification, error) {
+	query := r.sqlDB.WithContext(ctx).Preload("Actor").
+		Where("recipient_id = ?", userID).
+		Order("updated_at DESC, id DESC").
+		Offset(offset).Limit(limit)
+	if unreadOnly {
+		query = query.Where(map[string]any{"read": false})
+	}
+
+	notifications := []db.Notification{}
+	err := query.Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *GormNotificationRepository) Get(ctx context.Context, id, userID string) (*db.Notification, error) {
+	var n db.Notification
+	err := r.sqlDB.WithContext(ctx).Preload("Actor").First(&n, "id = ? AND recipient_id = ?", id, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// MarkRead and MarkAllRead leave updated_at alone, reading a notification
+// isn't activity on the post.
+func (r *GormNotificationRepository) MarkRead(ctx context.Context, id, userID string) (bool, error) {
+	result := r.sqlDB.WithContext(ctx).Model(&db.Notification{}).
+		Where(map[string]any{"id": id, "recipient_id": userID, "read": false}).
+		UpdateColumn("read", true)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *GormNotificationRepository) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	result := r.sqlDB.WithContext(ctx).Model(&db.Notification{}).
+		Where(map[string]any{"recipient_id": userID, "read": false}).
+		UpdateColumn("read", true)
+	return result.RowsAffected, result.Error
+}
+
+func (r *GormNotificationRepository) CountUnread(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.sqlDB.WithContext(ctx).Model(&db.Notification{}).
+		Where(map[string]any{"recipient_id": userID, "read": false}).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,533 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// The memory repositories keep posts, users, likes and notifications in
+// process memory, for tests and for running handlers without a database. The
+// like and notification repositories attach to the post repository, so that
+// deleting a post removes its likes and notifications too. Outbox events are
+// only kept for Events, no relay publishes them.
+
+// MemoryUserRepository keeps users in process memory.
+type MemoryUserRepository struct {
+	mu    sync.Mutex
+	users map[string]db.User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[string]db.User)}
+}
+
+func (r *MemoryUserRepository) Get(_ context.Context, id string) (*db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) GetMany(_ context.Context, ids []string) ([]db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := []db.User{}
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *MemoryUserRepository) Create(_ context.Context, user *db.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.ID]; ok {
+		return fmt.Errorf("user %s already exists", user.ID)
+	}
+	for _, u := range r.users {
+		if u.NickName == user.NickName {
+			return fmt.Errorf("nick name %q is taken", user.NickName)
+		}
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+// MemoryPostRepository keeps posts in process memory. Authors are looked up
+// in users on every read.
+type MemoryPostRepository struct {
+	users *MemoryUserRepository
+
+	mu            sync.Mutex
+	posts         map[string]db.Post
+	events        []*blog.FeedEvent
+	likes         *MemoryLikeRepository
+	notifications *MemoryNotificationRepository
+}
+
+func NewMemoryPostRepository(users *MemoryUserRepository) *MemoryPostRepository {
+	return &MemoryPostRepository{users: users, posts: make(map[string]db.Post)}
+}
+
+// withAuthor returns the post as stored with its current author.
+func (r *MemoryPostRepository) withAuthor(post db.Post) db.Post {
+	post.Author = db.User{}
+	if author, err := r.users.Get(context.Background(), post.AuthorID); err == nil {
+		post.Author = *author
+	}
+	return post
+}
+
+func (r *MemoryPostRepository) Get(_ context.Context, id string) (*db.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	post = r.withAuthor(post)
+	return &post, nil
+}
+
+func (r *MemoryPostRepository) GetMany(_ context.Context, ids []string) ([]db.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	posts := []db.Post{}
+	for _, id := range ids {
+		if post, ok := r.posts[id]; ok {
+			posts = append(posts, r.withAuthor(post))
+		}
+	}
+	return posts, nil
+}
+
+func (r *MemoryPostRepository) Exists(_ context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.posts[id]
+	return ok, nil
+}
+
+func (r *MemoryPostRepository) Newest(_ context.Context, offset, limit int) ([]db.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	all := make([]db.Post, 0, len(r.posts))
+	for _, post := range r.posts {
+		all = append(all, post)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].CreatedAt.After(all[j].CreatedAt)
+		}
+		// Only for a stable order, ids don't sort by age.
+		return all[i].ID > all[j].ID
+	})
+
+	posts := []db.Post{}
+	for i := offset; i < len(all) && len(posts) < limit; i++ {
+		posts = append(posts, r.withAuthor(all[i]))
+	}
+	return posts, nil
+}
+
+func (r *MemoryPostRepository) Create(_ context.Context, post *db.Post, event *blog.FeedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.posts[post.ID]; ok {
+		return fmt.Errorf("post %s already exists", post.ID)
+	}
+	r.posts[post.ID] = *post
+	r.record(event)
+	return nil
+}
+
+func (r *MemoryPostRepository) Update(_ context.Context, post *db.Post, event *blog.FeedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.posts[post.ID] = *post
+	r.record(event)
+	return nil
+}
+
+func (r *MemoryPostRepository) Delete(_ context.Context, id string, event *blog.FeedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.posts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.posts, id)
+	if r.likes != nil {
+		r.likes.dropPost(id)
+	}
+	if r.notifications != nil {
+		r.notifications.dropPost(id)
+	}
+	r.record(event)
+	return nil
+}
+
+func (r *MemoryPostRepository) record(event *blog.FeedEvent) {
+	r.events = recordEvent(r.events, event)
+}
+
+func recordEvent(events []*blog.FeedEvent, event *blog.FeedEvent) []*blog.FeedEvent {
+	if event == nil {
+		return events
+	}
+	return append(events, proto.Clone(event).(*blog.FeedEvent))
+}
+
+// Events returns the events recorded with post changes, oldest first.
+func (r *MemoryPostRepository) Events() []*blog.FeedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*blog.FeedEvent(nil), r.events...)
+}
+
+// MemoryLikeRepository keeps likes in process memory.
+type MemoryLikeRepository struct {
+	mu     sync.Mutex
+	likes  map[string]map[string]db.Like // by post, then user
+	events []*blog.FeedEvent
+}
+
+// NewMemoryLikeRepository returns an empty like repository whose likes are
+// removed along with their post from posts.
+func NewMemoryLikeRepository(posts *MemoryPostRepository) *MemoryLikeRepository {
+	r := &MemoryLikeRepository{likes: make(map[string]map[string]db.Like)}
+	posts.mu.Lock()
+	posts.likes = r
+	posts.mu.Unlock()
+	return r
+}
+
+func (r *MemoryLikeRepository) ForPosts(_ context.Context, postIDs []string) ([]db.Like, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	likes := []db.Like{}
+	for _, postID := range postIDs {
+		for _, like := range r.likes[postID] {
+			likes = append(likes, like)
+		}
+	}
+	return likes, nil
+}
+
+func (r *MemoryLikeRepository) Likers(_ context.Context, postID string, offset, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	likes := make([]db.Like, 0, len(r.likes[postID]))
+	for _, like := range r.likes[postID] {
+		likes = append(likes, like)
+	}
+	userIDs := []string{}
+	for _, like := range newestLikes(likes, offset, limit, func(like db.Like) string { return like.UserID }) {
+		userIDs = append(userIDs, like.UserID)
+	}
+	return userIDs, nil
+}
+
+func (r *MemoryLikeRepository) Liked(_ context.Context, userID string, offset, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var likes []db.Like
+	for _, postLikes := range r.likes {
+		if like, ok := postLikes[userID]; ok {
+			likes = append(likes, like)
+		}
+	}
+	postIDs := []string{}
+	for _, like := range newestLikes(likes, offset, limit, func(like db.Like) string { return like.PostID }) {
+		postIDs = append(postIDs, like.PostID)
+	}
+	return postIDs, nil
+}
+
+// newestLikes sorts likes newest first, breaking ties by key descending like
+// the SQL queries, and returns the requested page.
+func newestLikes(likes []db.Like, offset, limit int, key func(db.Like) string) []db.Like {
+	sort.Slice(likes, func(i, j int) bool {
+		if !likes[i].CreatedAt.Equal(likes[j].CreatedAt) {
+			return likes[i].CreatedAt.After(likes[j].CreatedAt)
+		}
+		return key(likes[i]) > key(likes[j])
+	})
+	if offset >= len(likes) {
+		return nil
+	}
+	likes = likes[offset:]
+	if len(likes) > limit {
+		likes = likes[:limit]
+	}
+	return likes
+}
+
+func (r *MemoryLikeRepository) Save(_ context.Context, postID, userID string, state func() (bool, error), event *blog.FeedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	liked, err := state()
+	if err != nil {
+		return err
+	}
+	r.set(postID, userID, liked)
+	r.events = recordEvent(r.events, event)
+	return nil
+}
+
+func (r *MemoryLikeRepository) Apply(_ context.Context, postID, userID string, mode LikeMode) (liked bool, total int64, changed bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, wasLiked := r.likes[postID][userID]
+	liked = mode == LikeSet || (mode == LikeToggle && !wasLiked)
+	changed = r.set(postID, userID, liked)
+	total = int64(len(r.likes[postID]))
+	if changed {
+		r.events = recordEvent(r.events, &blog.FeedEvent{
+			Type:       blog.FeedEvent_LIKES_CHANGED,
+			PostId:     postID,
+			LikesCount: int32(total),
+		})
+	}
+	return liked, total, changed, nil
+}
+
+// set stores the like state and reports whether it changed.
+func (r *MemoryLikeRepository) set(postID, userID string, liked bool) bool {
+	_, wasLiked := r.likes[postID][userID]
+	switch {
+	case liked && !wasLiked:
+		if r.likes[postID] == nil {
+			r.likes[postID] = make(map[string]db.Like)
+		}
+		r.likes[postID][userID] = db.Like{PostID: postID, UserID: userID, CreatedAt: time.Now()}
+	case !liked && wasLiked:
+		delete(r.likes[postID], userID)
+	default:
+		return false
+	}
+	return true
+}
+
+func (r *MemoryLikeRepository) Count(context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, postLikes := range r.likes {
+		count += int64(len(postLikes))
+	}
+	return count, nil
+}
+
+func (r *MemoryLikeRepository) Import(_ context.Context, likes []db.Like) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var imported int64
+	for _, like := range likes {
+		if _, ok := r.likes[like.PostID][like.UserID]; ok {
+			continue
+		}
+		if r.likes[like.PostID] == nil {
+			r.likes[like.PostID] = make(map[string]db.Like)
+		}
+		r.likes[like.PostID][like.UserID] = like
+		imported++
+	}
+	return imported, nil
+}
+
+func (r *MemoryLikeRepository) Each(_ context.Context, fn func(like db.Like) error) error {
+	r.mu.Lock()
+	var likes []db.Like
+	for _, postLikes := range r.likes {
+		for _, like := range postLikes {
+			likes = append(likes, like)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(likes, func(i, j int) bool {
+		if likes[i].PostID != likes[j].PostID {
+			return likes[i].PostID < likes[j].PostID
+		}
+		return likes[i].UserID < likes[j].UserID
+	})
+	for _, like := range likes {
+		if err := fn(like); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryLikeRepository) dropPost(postID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.likes, postID)
+}
+
+// Events returns the events recorded with like changes, oldest first.
+func (r *MemoryLikeRepository) Events() []*blog.FeedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*blog.FeedEvent(nil), r.events...)
+}
+
+// MemoryNotificationRepository keeps notifications in process memory. Actors
+// are looked up in users on every read.
+type MemoryNotificationRepository struct {
+	users *MemoryUserRepository
+
+	mu            sync.Mutex
+	notifications map[string]db.Notification
+	actors        map[string]map[string]bool // by notification, then actor
+}
+
+// NewMemoryNotificationRepository returns an empty notification repository
+// whose notifications are removed along with their post from posts.
+func NewMemoryNotificationRepository(users *MemoryUserRepository, posts *MemoryPostRepository) *MemoryNotificationRepository {
+	r := &MemoryNotificationRepository{
+		users:         users,
+		notifications: make(map[string]db.Notification),
+		actors:        make(map[string]map[string]bool),
+	}
+	posts.mu.Lock()
+	posts.notifications = r
+	posts.mu.Unlock()
+	return r
+}
+
+// withActor returns the notification as stored with its current actor.
+func (r *MemoryNotificationRepository) withActor(n db.Notification) db.Notification {
+	n.Actor = db.User{}
+	if actor, err := r.users.Get(context.Background(), n.ActorID); err == nil {
+		n.Actor = *actor
+	}
+	return n
+}
+
+func (r *MemoryNotificationRepository) Add(_ context.Context, n *db.Notification) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, unread := range r.notifications {
+		if unread.Read || unread.RecipientID != n.RecipientID || unread.Kind != n.Kind || unread.PostID != n.PostID {
+			continue
+		}
+		if r.actors[id][n.ActorID] {
+			// Already counted, e.g. a like after an unlike.
+			return false, nil
+		}
+		r.actors[id][n.ActorID] = true
+		unread.ActorID = n.ActorID
+		unread.ActorCount++
+		unread.UpdatedAt = n.UpdatedAt
+		r.notifications[id] = unread
+		return false, nil
+	}
+
+	if _, ok := r.notifications[n.ID]; ok {
+		return false, fmt.Errorf("notification %s already exists", n.ID)
+	}
+	stored := *n
+	stored.Actor = db.User{}
+	r.notifications[n.ID] = stored
+	r.actors[n.ID] = map[string]bool{n.ActorID: true}
+	return true, nil
+}
+
+func (r *MemoryNotificationRepository) List(_ context.Context, userID string, unreadOnly bool, offset, limit int) ([]db.Notification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []db.Notification
+	for _, n := range r.notifications {
+		if n.RecipientID == userID && !(unreadOnly && n.Read) {
+			all = append(all, n)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].UpdatedAt.Equal(all[j].UpdatedAt) {
+			return all[i].UpdatedAt.After(all[j].UpdatedAt)
+		}
+		return all[i].ID > all[j].ID
+	})
+
+	notifications := []db.Notification{}
+	for i := offset; i < len(all) && len(notifications) < limit; i++ {
+		notifications = append(notifications, r.withActor(all[i]))
+	}
+	return notifications, nil
+}
+
+func (r *MemoryNotificationRepository) Get(_ context.Context, id, userID string) (*db.Notification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n, ok := r.notifications[id]
+	if !ok || n.RecipientID != userID {
+		return nil, ErrNotFound
+	}
+	n = r.withActor(n)
+	return &n, nil
+}
+
+func (r *MemoryNotificationRepository) MarkRead(_ context.Context, id, userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n, ok := r.notifications[id]
+	if !ok || n.RecipientID != userID || n.Read {
+		return false, nil
+	}
+	n.Read = true
+	r.notifications[id] = n
+	return true, nil
+}
+
+func (r *MemoryNotificationRepository) MarkAllRead(_ context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var marked int64
+	for id, n := range r.notifications {
+		if n.RecipientID == userID && !n.Read {
+			n.Read = true
+			r.notifications[id] = n
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (r *MemoryNotificationRepository) CountUnread(_ context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, n := range r.notifications {
+		if n.RecipientID == userID && !n.Read {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryNotificationRepository) dropPost(postID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, n := range r.notifications {
+		if n.PostID == postID {
+			delete(r.notifications, id)
+			delete(r.actors, id)
+		}
+	}
+}
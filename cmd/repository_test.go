@@ -0,0 +1,494 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Every repository implementation runs the same contract. The gorm one runs
+// on a fresh SQLite file, or on TEST_DATABASE_URL if set, e.g. a scratch
+// Postgres or MySQL whose tables the test empties.
+
+type testRepositories struct {
+	posts         PostRepository
+	users         UserRepository
+	likes         LikeRepository
+	notifications NotificationRepository
+}
+
+var repositories = map[string]func(t *testing.T) testRepositories{
+	"memory": func(t *testing.T) testRepositories {
+		users := NewMemoryUserRepository()
+		posts := NewMemoryPostRepository(users)
+		return testRepositories{
+			posts:         posts,
+			users:         users,
+			likes:         NewMemoryLikeRepository(posts),
+			notifications: NewMemoryNotificationRepository(users, posts),
+		}
+	},
+	"gorm": func(t *testing.T) testRepositories {
+		sqlDB := newTestDatabase(t)
+		return testRepositories{
+			posts:         NewGormPostRepository(sqlDB),
+			users:         NewGormUserRepository(sqlDB),
+			likes:         NewGormLikeRepository(sqlDB),
+			notifications: NewGormNotificationRepository(sqlDB),
+		}
+	},
+}
+
+// newTestDatabase returns an empty, migrated database.
+func newTestDatabase(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		dsn = "sqlite://" + filepath.Join(t.TempDir(), "blog.db")
+	}
+	sqlDB, err := db.InitDB(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if conn, err := sqlDB.DB(); err == nil {
+			conn.Close()
+		}
+	})
+	for _, model := range []any{&db.WebhookDelivery{}, &db.WebhookEventType{}, &db.Webhook{}, &db.NotificationActor{}, &db.Notification{}, &db.Like{}, &db.OutboxEvent{}, &db.Post{}, &db.User{}} {
+		require.NoError(t, sqlDB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(model).Error)
+	}
+	return sqlDB
+}
+
+// slowPostRepository stands in for a slow database: it holds every Newest
+// call for delay and counts them.
+type slowPostRepository struct {
+	PostRepository
+	delay  time.Duration
+	newest atomic.Int32
+}
+
+func (r *slowPostRepository) Newest(ctx context.Context, offset, limit int) ([]db.Post, error) {
+	r.newest.Add(1)
+	time.Sleep(r.delay)
+	return r.PostRepository.Newest(ctx, offset, limit)
+}
+
+func TestRepositoryContract(t *testing.T) {
+	for name, newRepositories := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := newRepositories(t)
+			posts, users := repos.posts, repos.users
+
+			_, err := users.Get(ctx, "user-1")
+			require.ErrorIs(t, err, ErrNotFound)
+			_, err = posts.Get(ctx, "post-1")
+			require.ErrorIs(t, err, ErrNotFound)
+
+			naruto := db.User{ID: "user-1", NickName: "naruto_uzumaki", PhotoURL: "https://naruto-photo.jpg"}
+			require.NoError(t, users.Create(ctx, &naruto))
+			require.NoError(t, users.Create(ctx, &db.User{ID: "user-2", NickName: "tanjiro_kamada"}))
+			require.Error(t, users.Create(ctx, &db.User{ID: "user-1", NickName: "someone_else"}))
+			require.Error(t, users.Create(ctx, &db.User{ID: "user-3", NickName: "naruto_uzumaki"}))
+
+			user, err := users.Get(ctx, "user-1")
+			require.NoError(t, err)
+			require.Equal(t, naruto, *user)
+			many, err := users.GetMany(ctx, []string{"user-2", "user-9", "user-1"})
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"user-1", "user-2"}, userIDs(many))
+			many, err = users.GetMany(ctx, nil)
+			require.NoError(t, err)
+			require.Empty(t, many)
+
+			// Posts are stored without their author and read with it.
+			start := time.Date(2025, 3, 26, 13, 11, 0, 0, time.UTC)
+			for i, id := range []string{"post-1", "post-2", "post-3"} {
+				post := db.Post{ID: id, AuthorID: "user-1", Body: "Post by Naruto!", CreatedAt: start.Add(time.Duration(i) * time.Minute)}
+				require.NoError(t, posts.Create(ctx, &post, &blog.FeedEvent{Type: blog.FeedEvent_POST_CREATED, PostId: id}))
+			}
+			require.Error(t, posts.Create(ctx, &db.Post{ID: "post-1", AuthorID: "user-2", Body: "again"}, nil))
+
+			post, err := posts.Get(ctx, "post-2")
+			require.NoError(t, err)
+			require.Equal(t, "user-1", post.AuthorID)
+			require.Equal(t, naruto, post.Author)
+			require.True(t, post.CreatedAt.Equal(start.Add(time.Minute)))
+
+			exists, err := posts.Exists(ctx, "post-3")
+			require.NoError(t, err)
+			require.True(t, exists)
+			exists, err = posts.Exists(ctx, "post-9")
+			require.NoError(t, err)
+			require.False(t, exists)
+
+			found, err := posts.GetMany(ctx, []string{"post-3", "post-9", "post-1"})
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"post-1", "post-3"}, postIDs(found))
+			for _, p := range found {
+				require.Equal(t, naruto, p.Author)
+			}
+
+			newest, err := posts.Newest(ctx, 0, 2)
+			require.NoError(t, err)
+			require.Equal(t, []string{"post-3", "post-2"}, postIDs(newest))
+			newest, err = posts.Newest(ctx, 2, 10)
+			require.NoError(t, err)
+			require.Equal(t, []string{"post-1"}, postIDs(newest))
+			newest, err = posts.Newest(ctx, 3, 10)
+			require.NoError(t, err)
+			require.NotNil(t, newest)
+			require.Empty(t, newest)
+
+			post.Body = "Edited by Naruto!"
+			post.CreatedAt = start.Add(time.Hour)
+			require.NoError(t, posts.Update(ctx, post, nil))
+			post, err = posts.Get(ctx, "post-2")
+			require.NoError(t, err)
+			require.Equal(t, "Edited by Naruto!", post.Body)
+			newest, err = posts.Newest(ctx, 0, 1)
+			require.NoError(t, err)
+			require.Equal(t, []string{"post-2"}, postIDs(newest))
+
+			require.NoError(t, posts.Delete(ctx, "post-2", &blog.FeedEvent{Type: blog.FeedEvent_POST_DELETED, PostId: "post-2"}))
+			_, err = posts.Get(ctx, "post-2")
+			require.ErrorIs(t, err, ErrNotFound)
+			require.ErrorIs(t, posts.Delete(ctx, "post-2", nil), ErrNotFound)
+			newest, err = posts.Newest(ctx, 0, 10)
+			require.NoError(t, err)
+			require.Equal(t, []string{"post-3", "post-1"}, postIDs(newest))
+
+			// Generated ids sort below legacy ones, only created_at orders.
+			for i, id := range []string{"post-01JQ3Y7ZK9V4B6T1X2N5R8M0CD", "post-01JQ3Y7ZK9V4B6T1X2N5R8M0CE"} {
+				post := db.Post{ID: id, AuthorID: "user-2", Body: "Post by Tanjiro!", CreatedAt: start.Add(time.Duration(i+2) * time.Minute)}
+				require.NoError(t, posts.Create(ctx, &post, nil))
+			}
+			newest, err = posts.Newest(ctx, 0, 10)
+			require.NoError(t, err)
+			require.Equal(t, []string{"post-01JQ3Y7ZK9V4B6T1X2N5R8M0CE", "post-3", "post-01JQ3Y7ZK9V4B6T1X2N5R8M0CD", "post-1"}, postIDs(newest))
+		})
+	}
+}
+
+func TestLikeRepositoryContract(t *testing.T) {
+	for name, newRepositories := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := newRepositories(t)
+			likes := repos.likes
+			for _, id := range []string{"user-1", "user-2", "user-3"} {
+				require.NoError(t, repos.users.Create(ctx, &db.User{ID: id, NickName: id}))
+			}
+			for _, id := range []string{"post-1", "post-2"} {
+				require.NoError(t, repos.posts.Create(ctx, &db.Post{ID: id, AuthorID: "user-1", Body: "Post by Naruto!"}, nil))
+			}
+
+			liked, total, changed, err := likes.Apply(ctx, "post-1", "user-2", LikeSet)
+			require.NoError(t, err)
+			require.Equal(t, []any{true, int64(1), true}, []any{liked, total, changed})
+			liked, total, changed, err = likes.Apply(ctx, "post-1", "user-2", LikeSet)
+			require.NoError(t, err)
+			require.Equal(t, []any{true, int64(1), false}, []any{liked, total, changed})
+			liked, total, changed, err = likes.Apply(ctx, "post-1", "user-3", LikeToggle)
+			require.NoError(t, err)
+			require.Equal(t, []any{true, int64(2), true}, []any{liked, total, changed})
+			liked, total, changed, err = likes.Apply(ctx, "post-1", "user-1", LikeUnset)
+			require.NoError(t, err)
+			require.Equal(t, []any{false, int64(2), false}, []any{liked, total, changed})
+
+			require.NoError(t, likes.Save(ctx, "post-2", "user-2", likeState(true), &blog.FeedEvent{Type: blog.FeedEvent_LIKES_CHANGED, PostId: "post-2", LikesCount: 1}))
+			require.NoError(t, likes.Save(ctx, "post-2", "user-2", likeState(true), nil))
+
+			start := time.Date(2025, 3, 26, 13, 11, 0, 0, time.UTC)
+			imported := []db.Like{
+				{PostID: "post-2", UserID: "user-1", CreatedAt: start},
+				{PostID: "post-2", UserID: "user-3", CreatedAt: start},
+				{PostID: "post-1", UserID: "user-1", CreatedAt: start.Add(time.Minute)},
+				{PostID: "post-2", UserID: "user-2", CreatedAt: start},
+			}
+			added, err := likes.Import(ctx, imported)
+			require.NoError(t, err)
+			require.EqualValues(t, 3, added)
+			added, err = likes.Import(ctx, imported)
+			require.NoError(t, err)
+			require.Zero(t, added)
+
+			count, err := likes.Count(ctx)
+			require.NoError(t, err)
+			require.EqualValues(t, 6, count)
+
+			// Newest like first, ties broken by id.
+			likers, err := likes.Likers(ctx, "post-2", 0, 10)
+			require.NoError(t, err)
+			require.Equal(t, []string{"user-2", "user-3", "user-1"}, likers)
+			likers, err = likes.Likers(ctx, "post-2", 1, 1)
+			require.NoError(t, err)
+			require.Equal(t, []string{"user-3"}, likers)
+			liked2, err := likes.Liked(ctx, "user-1", 0, 10)
+			require.NoError(t, err)
+			require.Equal(t, []string{"post-1", "post-2"}, liked2)
+
+			forPosts, err := likes.ForPosts(ctx, []string{"post-2", "post-9"})
+			require.NoError(t, err)
+			require.Len(t, forPosts, 3)
+			forPosts, err = likes.ForPosts(ctx, nil)
+			require.NoError(t, err)
+			require.Empty(t, forPosts)
+
+			var each []string
+			require.NoError(t, likes.Each(ctx, func(like db.Like) error {
+				each = append(each, like.PostID)
+				return nil
+			}))
+			require.Equal(t, []string{"post-1", "post-1", "post-1", "post-2", "post-2", "post-2"}, each)
+
+			require.NoError(t, likes.Save(ctx, "post-2", "user-2", likeState(false), nil))
+			likers, err = likes.Likers(ctx, "post-2", 0, 10)
+			require.NoError(t, err)
+			require.Equal(t, []string{"user-3", "user-1"}, likers)
+
+			// Likes go with their post.
+			require.NoError(t, repos.posts.Delete(ctx, "post-1", nil))
+			likers, err = likes.Likers(ctx, "post-1", 0, 10)
+			require.NoError(t, err)
+			require.Empty(t, likers)
+			count, err = likes.Count(ctx)
+			require.NoError(t, err)
+			require.EqualValues(t, 2, count)
+		})
+	}
+}
+
+func likeState(liked bool) func() (bool, error) {
+	return func() (bool, error) { return liked, nil }
+}
+
+// Saves racing each other store the state the like store holds when they
+// persist, not the one they were started with.
+func TestLikeRepositorySavesLatestState(t *testing.T) {
+	for name, newRepositories := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := newRepositories(t)
+			require.NoError(t, repos.users.Create(ctx, &db.User{ID: "user-1", NickName: "naruto_uzumaki"}))
+			require.NoError(t, repos.posts.Create(ctx, &db.Post{ID: "post-1", AuthorID: "user-1", Body: "Post by Naruto!"}, nil))
+
+			var mu sync.Mutex
+			stored := false
+			current := func() (bool, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return stored, nil
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 20)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(liked bool) {
+					defer wg.Done()
+					mu.Lock()
+					stored = liked
+					mu.Unlock()
+					errs <- repos.likes.Save(ctx, "post-1", "user-1", current, nil)
+				}(i%2 == 0)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				require.NoError(t, err)
+			}
+
+			likers, err := repos.likes.Likers(ctx, "post-1", 0, 10)
+			require.NoError(t, err)
+			if stored {
+				require.Equal(t, []string{"user-1"}, likers)
+			} else {
+				require.Empty(t, likers)
+			}
+
+			failed := errors.New("no state")
+			err = repos.likes.Save(ctx, "post-1", "user-1", func() (bool, error) { return false, failed }, nil)
+			require.ErrorIs(t, err, failed)
+		})
+	}
+}
+
+func TestNotificationRepositoryContract(t *testing.T) {
+	for name, newRepositories := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := newRepositories(t)
+			notifications := repos.notifications
+			for _, id := range []string{"user-1", "user-2", "user-3"} {
+				require.NoError(t, repos.users.Create(ctx, &db.User{ID: id, NickName: "nick-" + id}))
+			}
+			require.NoError(t, repos.posts.Create(ctx, &db.Post{ID: "post-1", AuthorID: "user-1", Body: "Post by Naruto!"}, nil))
+
+			start := time.Date(2025, 3, 26, 13, 11, 0, 0, time.UTC)
+			add := func(id, actorID string, minute int) bool {
+				at := start.Add(time.Duration(minute) * time.Minute)
+				created, err := notifications.Add(ctx, &db.Notification{
+					ID: id, RecipientID: "user-1", Kind: notificationPostLiked, PostID: "post-1",
+					ActorID: actorID, ActorCount: 1, CreatedAt: at, UpdatedAt: at,
+				})
+				require.NoError(t, err)
+				return created
+			}
+
+			require.True(t, add("notification-1", "user-2", 0))
+			require.False(t, add("notification-2", "user-3", 1))
+			// The same actor again, e.g. a like after an unlike, isn't counted.
+			require.False(t, add("notification-3", "user-3", 2))
+
+			list, err := notifications.List(ctx, "user-1", false, 0, 10)
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+			require.Equal(t, "notification-1", list[0].ID)
+			require.Equal(t, "user-3", list[0].ActorID)
+			require.Equal(t, "nick-user-3", list[0].Actor.NickName)
+			require.Equal(t, 2, list[0].ActorCount)
+			count, err := notifications.CountUnread(ctx, "user-1")
+			require.NoError(t, err)
+			require.EqualValues(t, 1, count)
+
+			marked, err := notifications.MarkRead(ctx, "notification-1", "user-2")
+			require.NoError(t, err)
+			require.False(t, marked)
+			marked, err = notifications.MarkRead(ctx, "notification-1", "user-1")
+			require.NoError(t, err)
+			require.True(t, marked)
+			marked, err = notifications.MarkRead(ctx, "notification-1", "user-1")
+			require.NoError(t, err)
+			require.False(t, marked)
+
+			_, err = notifications.Get(ctx, "notification-1", "user-2")
+			require.ErrorIs(t, err, ErrNotFound)
+			n, err := notifications.Get(ctx, "notification-1", "user-1")
+			require.NoError(t, err)
+			require.True(t, n.Read)
+			require.Equal(t, "nick-user-3", n.Actor.NickName)
+
+			// Once read, the next activity starts a new notification.
+			require.True(t, add("notification-4", "user-2", 3))
+			list, err = notifications.List(ctx, "user-1", false, 0, 10)
+			require.NoError(t, err)
+			require.Equal(t, []string{"notification-4", "notification-1"}, notificationIDs(list))
+			list, err = notifications.List(ctx, "user-1", false, 1, 10)
+			require.NoError(t, err)
+			require.Equal(t, []string{"notification-1"}, notificationIDs(list))
+			list, err = notifications.List(ctx, "user-1", true, 0, 10)
+			require.NoError(t, err)
+			require.Equal(t, []string{"notification-4"}, notificationIDs(list))
+
+			all, err := notifications.MarkAllRead(ctx, "user-1")
+			require.NoError(t, err)
+			require.EqualValues(t, 1, all)
+			count, err = notifications.CountUnread(ctx, "user-1")
+			require.NoError(t, err)
+			require.Zero(t, count)
+
+			// Notifications go with their post.
+			require.NoError(t, repos.posts.Delete(ctx, "post-1", nil))
+			list, err = notifications.List(ctx, "user-1", false, 0, 10)
+			require.NoError(t, err)
+			require.Empty(t, list)
+		})
+	}
+}
+
+// A server on memory repositories alone has no database to fall back on.
+func TestMemoryServerLikesAndNotifies(t *testing.T) {
+	ctx := context.Background()
+	users := NewMemoryUserRepository()
+	posts := NewMemoryPostRepository(users)
+	notifications := NewMemoryNotificationRepository(users, posts)
+	s := &Server{
+		Posts:         posts,
+		Users:         users,
+		LikeRepo:      NewMemoryLikeRepository(posts),
+		Notifications: notifications,
+		Cache:         NoopFeedCache{},
+		Likes:         NewMemoryLikeStore(100),
+	}
+	require.NoError(t, users.Create(ctx, &db.User{ID: "user-1", NickName: "naruto_uzumaki"}))
+	require.NoError(t, users.Create(ctx, &db.User{ID: "user-2", NickName: "tanjiro_kamado"}))
+	post, err := s.createPost(ctx, "user-1", "Post by Naruto!")
+	require.NoError(t, err)
+
+	liked, err := s.setLike(ctx, "user-2", post.ID, LikeSet)
+	require.NoError(t, err)
+	require.True(t, liked.IsLiked)
+	require.EqualValues(t, 1, liked.LikesCount)
+
+	likers, err := s.listLikers(ctx, post.ID, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"user-2"}, userIDs(likers))
+	likedPosts, err := s.listLikedPosts(ctx, "user-2", "user-2", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, likedPosts, 1)
+	require.Equal(t, post.ID, likedPosts[0].ID)
+
+	list, err := notifications.List(ctx, "user-1", true, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "tanjiro_kamado liked your post", notificationText(&list[0]))
+
+	// Without the like store, likes are decided by the repository alone.
+	s.Likes = NoopLikeStore{}
+	liked, err = s.setLike(ctx, "user-2", post.ID, LikeToggle)
+	require.NoError(t, err)
+	require.False(t, liked.IsLiked)
+	require.Zero(t, liked.LikesCount)
+}
+
+func TestMemoryPostRepositoryRecordsEvents(t *testing.T) {
+	ctx := context.Background()
+	users := NewMemoryUserRepository()
+	posts := NewMemoryPostRepository(users)
+	require.NoError(t, users.Create(ctx, &db.User{ID: "user-1", NickName: "naruto_uzumaki"}))
+
+	s := &Server{Posts: posts, Users: users, Cache: NoopFeedCache{}, Likes: NoopLikeStore{}}
+	created, err := s.createPost(ctx, "user-1", "Post by Naruto!")
+	require.NoError(t, err)
+	require.Equal(t, "naruto_uzumaki", created.Author.NickName)
+	_, err = s.updatePost(ctx, "user-2", created.ID, &postUpdate{Body: "Not mine"}, nil)
+	require.Error(t, err)
+	updated, err := s.updatePost(ctx, "user-1", created.ID, &postUpdate{Body: "Edited"}, nil)
+	require.NoError(t, err)
+	// Editing a post doesn't move it up the feed.
+	require.True(t, created.CreatedAt.Equal(updated.CreatedAt))
+
+	events := posts.Events()
+	require.Len(t, events, 2)
+	require.Equal(t, blog.FeedEvent_POST_CREATED, events[0].Type)
+	require.Equal(t, "Post by Naruto!", events[0].Post.Body)
+	require.Equal(t, blog.FeedEvent_POST_UPDATED, events[1].Type)
+	require.Equal(t, "Edited", events[1].Post.Body)
+}
+
+func userIDs(users []db.User) []string {
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+func notificationIDs(notifications []db.Notification) []string {
+	ids := make([]string, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.ID
+	}
+	return ids
+}
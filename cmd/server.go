@@ -2,13 +2,13 @@ package server
 
 import (
 	"context"
-	"fmt"
-	"time"
 
 	blog "go_grpc_blog/api"
 	"go_grpc_blog/db"
+	"go_grpc_blog/idgen"
 
 	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -18,7 +18,20 @@ import (
 type Server struct {
 	blog.UnimplementedBlogServiceServer
 	Sql_DB   *gorm.DB
-	Redis_DB *redis.Client
+	Redis_DB redis.UniversalClient
+	IDs      idgen.Generator
+	Posts    PostRepository
+	Users    UserRepository
+	LikeRepo LikeRepository
+	Cache    FeedCache
+	Likes    LikeStore
+	Near     *NearCache
+	Feed     *FeedHub
+	Outbox   *OutboxRelay
+
+	Notifications NotificationRepository
+
+	feedFills singleflight.Group
 }
 
 func NewServer(sqlDB *gorm.DB, redisAddr string) *Server {
@@ -35,235 +48,201 @@ func NewServer(sqlDB *gorm.DB, redisAddr string) *Server {
 	return server
 }
 
-func dbPostToProtoPost(dbPost *db.Post, userID string) *blog.Post {
-	return &blog.Post{
-		Id: dbPost.ID,
-		Author: &blog.User{
-			Id:       dbPost.Author.ID,
-			NickName: dbPost.Author.NickName,
-			PhotoUrl: dbPost.Author.PhotoURL,
-		},
-		Body:      dbPost.Body,
-		CreatedAt: dbPost.CreatedAt.Format("15:04:05 02.01.2006"),
+// newID returns a fresh id for a new entity, e.g. "post-01JQ...". Ids are
+// opaque: they sort by time among themselves but not against legacy ids like
+// "post-10", so anything ordered by age orders by created_at.
+func (s *Server) newID(prefix string) string {
+	if s.IDs == nil {
+		return prefix + idgen.Default.NewID()
 	}
+	return prefix + s.IDs.NewID()
 }
 
-func (s *Server) GetPosts(ctx context.Context, req *blog.GetPostsRequest) (*blog.GetPostsResponse, error) {
+// userIDFromContext returns the caller id sent in the "user-id" metadata.
+func userIDFromContext(ctx context.Context) (string, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+		return "", status.Error(codes.Unauthenticated, "missing metadata")
 	}
-	headers := md.Get("user-id")
-	if len(headers) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "user-id header is required")
+	userIDs := md.Get("user-id")
+	if len(userIDs) == 0 {
+		return "", status.Error(codes.Unauthenticated, "user-id header is required")
 	}
-	userID := headers[0]
+	return userIDs[0], nil
+}
 
-	dbPosts, err := GetCachedPosts(s, ctx)
-	if err != nil {
-		fmt.Println("Problems loading cache")
+// writerIDFromContext is userIDFromContext for the v1 CreatePost, UpdatePost
+// and DeletePost, which have always answered a request without metadata
+// with "missing user id".
+func writerIDFromContext(ctx context.Context) (string, error) {
+	if _, ok := metadata.FromIncomingContext(ctx); !ok {
+		return "", status.Error(codes.Unauthenticated, "missing user id")
 	}
+	return userIDFromContext(ctx)
+}
 
-	if dbPosts == nil {
-		result := s.Sql_DB.Preload("Author").Order("created_at desc").Limit(int(req.Limit)).Offset(int(req.Offset)).Find(&dbPosts)
-		if result.Error != nil {
-			return nil, status.Errorf(codes.Internal, "failed to fetch posts: %v", result.Error)
-		}
+func dbUserToProtoUser(dbUser *db.User) *blog.User {
+	return &blog.User{
+		Id:       dbUser.ID,
+		NickName: dbUser.NickName,
+		PhotoUrl: dbUser.PhotoURL,
 	}
+}
 
-	posts := make([]*blog.Post, len(dbPosts))
+func dbPostToProtoPost(dbPost *db.Post, userID string) *blog.Post {
+	return &blog.Post{
+		Id:        dbPost.ID,
+		Author:    dbUserToProtoUser(&dbPost.Author),
+		Body:      dbPost.Body,
+		CreatedAt: dbPost.CreatedAt.Format("15:04:05 02.01.2006"),
+	}
+}
 
-	pipe := s.Redis_DB.Pipeline()
-	likeCmds := make([]*redis.StringCmd, len(dbPosts))
-	userLikeCmds := make([]*redis.StringCmd, len(dbPosts))
+func likedPostToProtoPost(p *likedPost, userID string) *blog.Post {
+	post := dbPostToProtoPost(&p.Post, userID)
+	post.LikesCount = p.LikesCount
+	post.IsLiked = p.IsLiked
+	post.LikesUnknown = p.LikesUnknown
+	return post
+}
 
-	for i, p := range dbPosts {
-		postLikesKey := "post:" + p.ID + ":likes"
-		likeCmds[i] = pipe.HGet(ctx, postLikesKey, "total-likes")
-		userLikeCmds[i] = pipe.HGet(ctx, postLikesKey, userID)
+func (s *Server) GetPosts(ctx context.Context, req *blog.GetPostsRequest) (*blog.GetPostsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
-		return nil, status.Errorf(codes.Internal, "failed to fetch likes: %v", err)
+	likedPosts, err := s.getPosts(ctx, userID, int(req.Limit), int(req.Offset))
+	if err != nil {
+		return nil, err
 	}
 
-	for i, p := range dbPosts {
-		totalLikes, err := likeCmds[i].Int64()
-		if err != nil && err != redis.Nil {
-			totalLikes = 0
-		}
-		if err == redis.Nil {
-			if err := s.Redis_DB.HSet(ctx, "post:"+p.ID+":likes", "total-likes", 0).Err(); err != nil {
-				return nil, status.Errorf(codes.Internal, "failed to initialize likes count for post %s: %v", p.ID, err)
-			}
-		}
-
-		isLikedStr, err := userLikeCmds[i].Result()
-		isLiked := err == nil && isLikedStr == "1"
-
-		post := dbPostToProtoPost(&p, userID)
-		post.LikesCount = int32(totalLikes)
-		post.IsLiked = isLiked
-		posts[i] = post
+	posts := make([]*blog.Post, len(likedPosts))
+	for i := range likedPosts {
+		posts[i] = likedPostToProtoPost(&likedPosts[i], userID)
 	}
 
 	return &blog.GetPostsResponse{Posts: posts}, nil
 }
 
 func (s *Server) CreatePost(ctx context.Context, req *blog.CreatePostRequest) (*blog.CreatePostResponse, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "missing user id")
-	}
-	userIDs := md.Get("user-id")
-	if len(userIDs) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "user-id header is required")
+	authorID, err := writerIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
-	authorID := userIDs[0]
 
-	var user db.User
-	result := s.Sql_DB.First(&user, "id = ?", authorID)
-	if result.Error != nil {
-		return nil, status.Errorf(codes.NotFound, "user not found: %v", result.Error)
+	newPost, err := s.createPost(ctx, authorID, req.Body)
+	if err != nil {
+		return nil, err
 	}
 
-	newPost := db.Post{
-		ID:        fmt.Sprintf("post-%d", time.Now().UnixNano()),
-		Author:    user,
-		Body:      req.Body,
-		CreatedAt: time.Now(),
-	}
+	return &blog.CreatePostResponse{Post: dbPostToProtoPost(newPost, authorID)}, nil
+}
 
-	result = s.Sql_DB.Create(&newPost)
-	if result.Error != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create post: %v", result.Error)
+func (s *Server) UpdatePost(ctx context.Context, req *blog.UpdatePostRequest) (*blog.UpdatePostResponse, error) {
+	currentUserID, err := writerIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	s.Sql_DB.Preload("Author").First(&newPost, "id = ?", newPost.ID)
-	protoPost := dbPostToProtoPost(&newPost, authorID)
+	dbPost, err := s.updatePost(ctx, currentUserID, req.Id, &postUpdate{Body: req.Body}, req.GetUpdateMask().GetPaths())
+	if err != nil {
+		return nil, err
+	}
 
-	return &blog.CreatePostResponse{Post: protoPost}, nil
+	return &blog.UpdatePostResponse{Post: dbPostToProtoPost(dbPost, currentUserID)}, nil
 }
 
-func (s *Server) UpdatePost(ctx context.Context, req *blog.UpdatePostRequest) (*blog.UpdatePostResponse, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "missing user id")
-	}
-	userIDs := md.Get("user-id")
-	if len(userIDs) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "user-id header is required")
+func (s *Server) DeletePost(ctx context.Context, req *blog.DeletePostRequest) (*blog.DeletePostResponse, error) {
+	currentUserID, err := writerIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
-	currentUserID := userIDs[0]
 
-	var dbPost db.Post
-	result := s.Sql_DB.Preload("Author").First(&dbPost, "id = ?", req.Id)
-	if result.Error != nil {
-		return nil, status.Errorf(codes.NotFound, "post not found: %v", result.Error)
+	if err := s.deletePost(ctx, currentUserID, req.Id); err != nil {
+		return nil, err
 	}
 
-	if dbPost.Author.ID != currentUserID {
-		return nil, status.Error(codes.PermissionDenied, "only author can update the post")
+	return &blog.DeletePostResponse{}, nil
+}
+
+// ToggleLike is kept for older clients. Retrying it flips the like back, new
+// clients should use LikePost and UnlikePost.
+func (s *Server) ToggleLike(ctx context.Context, req *blog.ToggleLikeRequest) (*blog.ToggleLikeResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	dbPost.Body = req.Body
-	dbPost.CreatedAt = time.Now()
-	result = s.Sql_DB.Save(&dbPost)
-	if result.Error != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update post: %v", result.Error)
+	post, err := s.setLike(ctx, userID, req.PostId, LikeToggle)
+	if err != nil {
+		return nil, err
 	}
 
-	protoPost := dbPostToProtoPost(&dbPost, currentUserID)
-	return &blog.UpdatePostResponse{Post: protoPost}, nil
+	return &blog.ToggleLikeResponse{Post: likedPostToProtoPost(post, userID)}, nil
 }
 
-func (s *Server) DeletePost(ctx context.Context, req *blog.DeletePostRequest) (*blog.DeletePostResponse, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "missing user id")
-	}
-	userIDs := md.Get("user-id")
-	if len(userIDs) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "user-id header is required")
+func (s *Server) LikePost(ctx context.Context, req *blog.LikePostRequest) (*blog.LikePostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
-	currentUserID := userIDs[0]
 
-	var dbPost db.Post
-	result := s.Sql_DB.Preload("Author").First(&dbPost, "id = ?", req.Id)
-	if result.Error != nil {
-		return nil, status.Errorf(codes.NotFound, "post not found: %v", result.Error)
+	post, err := s.setLike(ctx, userID, req.PostId, LikeSet)
+	if err != nil {
+		return nil, err
 	}
 
-	if dbPost.Author.ID != currentUserID {
-		return nil, status.Error(codes.PermissionDenied, "only author can delete the post")
+	return &blog.LikePostResponse{Post: likedPostToProtoPost(post, userID)}, nil
+}
+
+func (s *Server) UnlikePost(ctx context.Context, req *blog.UnlikePostRequest) (*blog.UnlikePostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	result = s.Sql_DB.Delete(&dbPost)
-	if result.Error != nil {
-		return nil, status.Errorf(codes.Internal, "failed to delete post: %v", result.Error)
+	post, err := s.setLike(ctx, userID, req.PostId, LikeUnset)
+	if err != nil {
+		return nil, err
 	}
 
-	return &blog.DeletePostResponse{}, nil
+	return &blog.UnlikePostResponse{Post: likedPostToProtoPost(post, userID)}, nil
 }
 
-func (s *Server) ToggleLike(ctx context.Context, req *blog.ToggleLikeRequest) (*blog.ToggleLikeResponse, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+func (s *Server) ListLikers(ctx context.Context, req *blog.ListLikersRequest) (*blog.ListLikersResponse, error) {
+	if _, err := userIDFromContext(ctx); err != nil {
+		return nil, err
 	}
-	userIDs := md.Get("user-id")
-	if len(userIDs) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "user-id header is required")
+
+	dbUsers, err := s.listLikers(ctx, req.PostId, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
 	}
-	userID := userIDs[0]
 
-	var dbPost db.Post
-	result := s.Sql_DB.Preload("Author").First(&dbPost, "id = ?", req.PostId)
-	if result.Error != nil {
-		return nil, status.Errorf(codes.NotFound, "post not found: %v", result.Error)
+	users := make([]*blog.User, len(dbUsers))
+	for i := range dbUsers {
+		users[i] = dbUserToProtoUser(&dbUsers[i])
 	}
 
-	postLikesKey := "post:" + req.PostId + ":likes"
+	return &blog.ListLikersResponse{Users: users}, nil
+}
 
-	isLiked, err := s.Redis_DB.HGet(ctx, postLikesKey, userID).Bool()
-	if err != nil && err != redis.Nil {
-		return nil, status.Errorf(codes.Internal, "failed to check like status: %v", err)
+func (s *Server) ListLikedPosts(ctx context.Context, req *blog.ListLikedPostsRequest) (*blog.ListLikedPostsResponse, error) {
+	currentUserID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if isLiked {
-		if err := s.Redis_DB.HDel(ctx, postLikesKey, userID).Err(); err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to remove like from Redis: %v", err)
-		}
-		if err := s.Redis_DB.HIncrBy(ctx, postLikesKey, "total-likes", -1).Err(); err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to decrement total likes: %v", err)
-		}
-		isLiked = false
-	} else {
-		if err := s.Redis_DB.HSet(ctx, postLikesKey, userID, true).Err(); err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to add like to Redis: %v", err)
-		}
-		if err := s.Redis_DB.HIncrBy(ctx, postLikesKey, "total-likes", 1).Err(); err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to increment total likes: %v", err)
-		}
-		isLiked = true
+	likedPosts, err := s.listLikedPosts(ctx, currentUserID, req.UserId, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
 	}
 
-	protoPost := dbPostToProtoPost(&dbPost, userID)
-
-	totalLikes, err := s.Redis_DB.HGet(ctx, postLikesKey, "total-likes").Int64()
-	if err != nil && err != redis.Nil {
-		return nil, status.Errorf(codes.Internal, "failed to get total likes: %v", err)
+	posts := make([]*blog.Post, len(likedPosts))
+	for i := range likedPosts {
+		posts[i] = likedPostToProtoPost(&likedPosts[i], currentUserID)
 	}
-	if err == redis.Nil {
-		pipe := s.Redis_DB.Pipeline()
-		pipe.HSet(ctx, postLikesKey, "total-likes", totalLikes)
-		if _, err := pipe.Exec(ctx); err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to initialize Redis likes: %v", err)
-		}
-	}
-
-	protoPost.LikesCount = int32(totalLikes)
-	protoPost.IsLiked = isLiked
 
-	return &blog.ToggleLikeResponse{Post: protoPost}, nil
+	return &blog.ListLikedPostsResponse{Posts: posts}, nil
 }
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	blog "go_grpc_blog/api"
+	blogv2 "go_grpc_blog/api/v2"
+	"go_grpc_blog/db"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ServerV2 serves the blog.v2 API on top of the same handler logic as v1.
+// The only difference is that timestamps are google.protobuf.Timestamp.
+type ServerV2 struct {
+	blogv2.UnimplementedBlogServiceServer
+	*Server
+}
+
+func NewServerV2(s *Server) *ServerV2 {
+	return &ServerV2{Server: s}
+}
+
+func dbPostToProtoPostV2(dbPost *db.Post) *blogv2.Post {
+	return &blogv2.Post{
+		Id: dbPost.ID,
+		Author: &blogv2.User{
+			Id:       dbPost.Author.ID,
+			NickName: dbPost.Author.NickName,
+			PhotoUrl: dbPost.Author.PhotoURL,
+		},
+		Body:      dbPost.Body,
+		CreatedAt: timestamppb.New(dbPost.CreatedAt),
+	}
+}
+
+func likedPostToProtoPostV2(p *likedPost) *blogv2.Post {
+	post := dbPostToProtoPostV2(&p.Post)
+	post.LikesCount = p.LikesCount
+	post.IsLiked = p.IsLiked
+	post.LikesUnknown = p.LikesUnknown
+	return post
+}
+
+func (s *ServerV2) GetPosts(ctx context.Context, req *blogv2.GetPostsRequest) (*blogv2.GetPostsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	likedPosts, err := s.getPosts(ctx, userID, int(req.Limit), int(req.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]*blogv2.Post, len(likedPosts))
+	for i := range likedPosts {
+		posts[i] = likedPostToProtoPostV2(&likedPosts[i])
+	}
+
+	return &blogv2.GetPostsResponse{Posts: posts}, nil
+}
+
+func (s *ServerV2) CreatePost(ctx context.Context, req *blogv2.CreatePostRequest) (*blogv2.CreatePostResponse, error) {
+	authorID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	newPost, err := s.createPost(ctx, authorID, req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blogv2.CreatePostResponse{Post: dbPostToProtoPostV2(newPost)}, nil
+}
+
+func (s *ServerV2) UpdatePost(ctx context.Context, req *blogv2.UpdatePostRequest) (*blogv2.UpdatePostResponse, error) {
+	currentUserID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dbPost, err := s.updatePost(ctx, currentUserID, req.Id, &postUpdate{Body: req.Body}, req.GetUpdateMask().GetPaths())
+	if err != nil {
+		return nil, err
+	}
+
+	return &blogv2.UpdatePostResponse{Post: dbPostToProtoPostV2(dbPost)}, nil
+}
+
+func (s *ServerV2) DeletePost(ctx context.Context, req *blogv2.DeletePostRequest) (*blogv2.DeletePostResponse, error) {
+	currentUserID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.deletePost(ctx, currentUserID, req.Id); err != nil {
+		return nil, err
+	}
+
+	return &blogv2.DeletePostResponse{}, nil
+}
+
+func (s *ServerV2) ToggleLike(ctx context.Context, req *blogv2.ToggleLikeRequest) (*blogv2.ToggleLikeResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.setLike(ctx, userID, req.PostId, LikeToggle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blogv2.ToggleLikeResponse{Post: likedPostToProtoPostV2(post)}, nil
+}
+
+func (s *ServerV2) LikePost(ctx context.Context, req *blogv2.LikePostRequest) (*blogv2.LikePostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.setLike(ctx, userID, req.PostId, LikeSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blogv2.LikePostResponse{Post: likedPostToProtoPostV2(post)}, nil
+}
+
+func (s *ServerV2) UnlikePost(ctx context.Context, req *blogv2.UnlikePostRequest) (*blogv2.UnlikePostResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.setLike(ctx, userID, req.PostId, LikeUnset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blogv2.UnlikePostResponse{Post: likedPostToProtoPostV2(post)}, nil
+}
+
+func (s *ServerV2) ListLikers(ctx context.Context, req *blogv2.ListLikersRequest) (*blogv2.ListLikersResponse, error) {
+	if _, err := userIDFromContext(ctx); err != nil {
+		return nil, err
+	}
+
+	dbUsers, err := s.listLikers(ctx, req.PostId, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*blogv2.User, len(dbUsers))
+	for i, u := range dbUsers {
+		users[i] = &blogv2.User{Id: u.ID, NickName: u.NickName, PhotoUrl: u.PhotoURL}
+	}
+
+	return &blogv2.ListLikersResponse{Users: users}, nil
+}
+
+func (s *ServerV2) ListLikedPosts(ctx context.Context, req *blogv2.ListLikedPostsRequest) (*blogv2.ListLikedPostsResponse, error) {
+	currentUserID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	likedPosts, err := s.listLikedPosts(ctx, currentUserID, req.UserId, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]*blogv2.Post, len(likedPosts))
+	for i := range likedPosts {
+		posts[i] = likedPostToProtoPostV2(&likedPosts[i])
+	}
+
+	return &blogv2.ListLikedPostsResponse{Posts: posts}, nil
+}
+
+func (s *ServerV2) WatchFeed(req *blogv2.WatchFeedRequest, stream blogv2.BlogService_WatchFeedServer) error {
+	return s.watchFeed(stream.Context(), req.LastEventId, func(event *blog.FeedEvent) error {
+		return stream.Send(feedEventToProtoFeedEventV2(event))
+	})
+}
+
+// feedEventToProtoFeedEventV2 converts a feed event as published, in v1
+// messages. Their created_at is a formatted local time, so posts in events
+// are only precise to the second.
+func feedEventToProtoFeedEventV2(event *blog.FeedEvent) *blogv2.FeedEvent {
+	v2 := &blogv2.FeedEvent{
+		Id:         event.Id,
+		Type:       blogv2.FeedEvent_Type(event.Type),
+		PostId:     event.PostId,
+		LikesCount: event.LikesCount,
+	}
+	if p := event.Post; p != nil {
+		v2.Post = &blogv2.Post{
+			Id:           p.Id,
+			Body:         p.Body,
+			LikesCount:   p.LikesCount,
+			IsLiked:      p.IsLiked,
+			LikesUnknown: p.LikesUnknown,
+		}
+		if a := p.Author; a != nil {
+			v2.Post.Author = &blogv2.User{Id: a.Id, NickName: a.NickName, PhotoUrl: a.PhotoUrl}
+		}
+		if createdAt, err := time.ParseInLocation("15:04:05 02.01.2006", p.CreatedAt, time.Local); err == nil {
+			v2.Post.CreatedAt = timestamppb.New(createdAt)
+		}
+	}
+	return v2
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	blog "go_grpc_blog/api"
+	blogv2 "go_grpc_blog/api/v2"
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestServerV2Likes(t *testing.T) {
+	ctx := context.Background()
+	users := NewMemoryUserRepository()
+	posts := NewMemoryPostRepository(users)
+	s := NewServerV2(&Server{
+		Posts:         posts,
+		Users:         users,
+		LikeRepo:      NewMemoryLikeRepository(posts),
+		Notifications: NewMemoryNotificationRepository(users, posts),
+		Cache:         NoopFeedCache{},
+		Likes:         NewMemoryLikeStore(100),
+	})
+	require.NoError(t, users.Create(ctx, &db.User{ID: "user-1", NickName: "naruto_uzumaki"}))
+	require.NoError(t, users.Create(ctx, &db.User{ID: "user-2", NickName: "tanjiro_kamado"}))
+	createdAt := time.Date(2025, 3, 26, 13, 11, 0, 0, time.UTC)
+	require.NoError(t, posts.Create(ctx, &db.Post{ID: "post-1", AuthorID: "user-1", Body: "Post by Naruto!", CreatedAt: createdAt}, nil))
+	asTanjiro := metadata.NewIncomingContext(ctx, metadata.Pairs("user-id", "user-2"))
+
+	liked, err := s.LikePost(asTanjiro, &blogv2.LikePostRequest{PostId: "post-1"})
+	require.NoError(t, err)
+	require.True(t, liked.Post.IsLiked)
+	require.EqualValues(t, 1, liked.Post.LikesCount)
+	require.True(t, liked.Post.CreatedAt.AsTime().Equal(createdAt))
+
+	likers, err := s.ListLikers(asTanjiro, &blogv2.ListLikersRequest{PostId: "post-1"})
+	require.NoError(t, err)
+	require.Len(t, likers.Users, 1)
+	require.Equal(t, "tanjiro_kamado", likers.Users[0].NickName)
+
+	likedPosts, err := s.ListLikedPosts(asTanjiro, &blogv2.ListLikedPostsRequest{UserId: "user-2"})
+	require.NoError(t, err)
+	require.Len(t, likedPosts.Posts, 1)
+	require.Equal(t, "post-1", likedPosts.Posts[0].Id)
+	require.True(t, likedPosts.Posts[0].IsLiked)
+
+	unliked, err := s.UnlikePost(asTanjiro, &blogv2.UnlikePostRequest{PostId: "post-1"})
+	require.NoError(t, err)
+	require.False(t, unliked.Post.IsLiked)
+	require.Zero(t, unliked.Post.LikesCount)
+}
+
+func TestFeedEventToProtoFeedEventV2(t *testing.T) {
+	createdAt := time.Date(2025, 3, 26, 13, 11, 7, 0, time.Local)
+	post := dbPostToProtoPost(&db.Post{ID: "post-1", Author: db.User{ID: "user-1", NickName: "naruto_uzumaki"}, Body: "Post by Naruto!", CreatedAt: createdAt}, "user-1")
+
+	event := feedEventToProtoFeedEventV2(&blog.FeedEvent{Id: "1-0", Type: blog.FeedEvent_POST_CREATED, PostId: "post-1", Post: post})
+	require.Equal(t, blogv2.FeedEvent_POST_CREATED, event.Type)
+	require.Equal(t, "1-0", event.Id)
+	require.Equal(t, "naruto_uzumaki", event.Post.Author.NickName)
+	require.True(t, event.Post.CreatedAt.AsTime().Equal(createdAt))
+
+	event = feedEventToProtoFeedEventV2(&blog.FeedEvent{Id: "1-1", Type: blog.FeedEvent_LIKES_CHANGED, PostId: "post-1", LikesCount: 3})
+	require.Equal(t, blogv2.FeedEvent_LIKES_CHANGED, event.Type)
+	require.EqualValues(t, 3, event.LikesCount)
+	require.Nil(t, event.Post)
+}
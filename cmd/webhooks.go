@@ -0,0 +1,565 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+	"go_grpc_blog/idgen"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gorm.io/gorm"
+)
+
+// Every event a webhook subscribes to gets a row in webhook_deliveries, which
+// doubles as the delivery log. The dispatcher on each replica polls for due
+// deliveries and claims one by pushing its next_attempt_at past a lease, so a
+// delivery is only sent by one replica at a time and is retried if that
+// replica dies mid-attempt. Deliveries that keep failing are marked dead and
+// pushed to the webhooks:dead_letter Redis list.
+
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryDead      = "dead"
+
+	webhookDeadLetterKey = "webhooks:dead_letter"
+	// webhookDeadLetterRetained bounds the dead letter list.
+	webhookDeadLetterRetained = 10000
+
+	defaultWebhookMaxAttempts  = 8
+	defaultWebhookBaseBackoff  = 10 * time.Second
+	defaultWebhookMaxBackoff   = time.Hour
+	defaultWebhookPollInterval = 5 * time.Second
+	defaultWebhookTimeout      = 10 * time.Second
+	webhookBatchSize           = 50
+)
+
+var deliveryStatuses = map[string]blog.Delivery_Status{
+	deliveryPending:   blog.Delivery_PENDING,
+	deliveryDelivered: blog.Delivery_DELIVERED,
+	deliveryDead:      blog.Delivery_DEAD,
+}
+
+// webhookEventTypes are the events a webhook can subscribe to.
+var webhookEventTypes = map[blog.FeedEvent_Type]bool{
+	blog.FeedEvent_POST_CREATED:  true,
+	blog.FeedEvent_POST_UPDATED:  true,
+	blog.FeedEvent_POST_DELETED:  true,
+	blog.FeedEvent_LIKES_CHANGED: true,
+}
+
+// WebhookDispatcher records and sends webhook deliveries.
+type WebhookDispatcher struct {
+	sqlDB  *gorm.DB
+	rdb    redis.UniversalClient
+	client *http.Client
+	wake   chan struct{}
+
+	IDs          idgen.Generator
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	// AllowPrivateTargets lets deliveries reach loopback, link-local and
+	// private addresses, for local development only.
+	AllowPrivateTargets bool
+}
+
+func NewWebhookDispatcher(sqlDB *gorm.DB, rdb redis.UniversalClient) *WebhookDispatcher {
+	d := &WebhookDispatcher{
+		sqlDB:        sqlDB,
+		rdb:          rdb,
+		wake:         make(chan struct{}, 1),
+		MaxAttempts:  defaultWebhookMaxAttempts,
+		BaseBackoff:  defaultWebhookBaseBackoff,
+		MaxBackoff:   defaultWebhookMaxBackoff,
+		PollInterval: defaultWebhookPollInterval,
+	}
+
+	// Targets are checked again when connecting, as a host name validated
+	// at creation can resolve to another address later, and redirects are
+	// dialled the same way. No proxy, it would do the dialling instead.
+	dialer := &net.Dialer{
+		Timeout: defaultWebhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if d.AllowPrivateTargets {
+				return nil
+			}
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return checkWebhookAddr(addr.Addr())
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	d.client = &http.Client{Timeout: defaultWebhookTimeout, Transport: transport}
+	return d
+}
+
+// checkWebhookAddr refuses addresses that webhooks must not reach: anything
+// on the server's own host or network, such as 127.0.0.1, the cloud metadata
+// service at 169.254.169.254 or RFC 1918 addresses.
+func checkWebhookAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() || !addr.IsValid() {
+		return fmt.Errorf("webhooks can't target %s", addr)
+	}
+	return nil
+}
+
+// Enqueue records a delivery of the event for every webhook subscribed to it.
+func (d *WebhookDispatcher) Enqueue(ctx context.Context, event *blog.FeedEvent) error {
+	var hooks []db.Webhook
+	err := d.sqlDB.WithContext(ctx).
+		Joins("JOIN webhook_event_types ON webhook_event_types.webhook_id = webhooks.id").
+		Where("webhook_event_types.event_type = ?", event.Type.String()).
+		Find(&hooks).Error
+	if err != nil {
+		return err
+	}
+
+	payload, err := protojson.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var deliveries []db.WebhookDelivery
+	for _, hook := range hooks {
+		deliveries = append(deliveries, db.WebhookDelivery{
+			ID:            d.newDeliveryID(),
+			WebhookID:     hook.ID,
+			EventID:       event.Id,
+			EventType:     event.Type.String(),
+			Payload:       string(payload),
+			Status:        deliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := d.sqlDB.WithContext(ctx).Create(&deliveries).Error; err != nil {
+		return err
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run sends due deliveries until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+		if err := d.deliverDue(ctx); err != nil {
+			log.Printf("🔴 Failed to send webhook deliveries: %v", err)
+		}
+	}
+}
+
+func (d *WebhookDispatcher) deliverDue(ctx context.Context) error {
+	var due []db.WebhookDelivery
+	err := d.sqlDB.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", deliveryPending, time.Now()).
+		Order("next_attempt_at").Limit(webhookBatchSize).
+		Find(&due).Error
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := range due {
+		delivery := &due[i]
+		if !d.claim(ctx, delivery) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// claim leases the delivery to this replica for the length of an attempt.
+func (d *WebhookDispatcher) claim(ctx context.Context, delivery *db.WebhookDelivery) bool {
+	result := d.sqlDB.WithContext(ctx).Model(&db.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, deliveryPending, delivery.NextAttemptAt).
+		Update("next_attempt_at", time.Now().Add(2*defaultWebhookTimeout))
+	if result.Error != nil {
+		log.Printf("🔴 Failed to claim webhook delivery %s: %v", delivery.ID, result.Error)
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *db.WebhookDelivery) {
+	var hook db.Webhook
+	if err := d.sqlDB.WithContext(ctx).First(&hook, "id = ?", delivery.WebhookID).Error; err != nil {
+		// The webhook was deleted together with its deliveries.
+		return
+	}
+
+	code, err := d.send(ctx, &hook, delivery)
+	dead := d.recordAttempt(delivery, code, err, time.Now())
+
+	if err := d.sqlDB.WithContext(ctx).Save(delivery).Error; err != nil {
+		log.Printf("🔴 Failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+	if dead {
+		if err := d.deadLetter(ctx, &hook, delivery); err != nil {
+			log.Printf("🔴 Failed to dead-letter webhook delivery %s: %v", delivery.ID, err)
+		}
+	}
+}
+
+// send POSTs the event to the webhook and returns the response status.
+func (d *WebhookDispatcher) send(ctx context.Context, hook *db.Webhook, delivery *db.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Blog-Event", strings.ToLower(delivery.EventType))
+	req.Header.Set("X-Blog-Delivery", delivery.ID)
+	req.Header.Set("X-Blog-Timestamp", timestamp)
+	req.Header.Set("X-Blog-Signature", "sha256="+signPayload(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// signPayload is the hex HMAC-SHA256 of "<timestamp>.<payload>". Signing the
+// timestamp lets receivers reject replayed requests.
+func signPayload(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// recordAttempt updates the delivery after an attempt and reports whether it
+// has been given up on.
+func (d *WebhookDispatcher) recordAttempt(delivery *db.WebhookDelivery, code int, err error, now time.Time) bool {
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	if err == nil {
+		delivery.Status = deliveryDelivered
+		delivery.LastError = ""
+		return false
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.MaxAttempts {
+		delivery.Status = deliveryDead
+		return true
+	}
+	delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	return false
+}
+
+// backoff doubles the wait after every failed attempt, up to MaxBackoff.
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	wait := d.BaseBackoff
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.MaxBackoff)
+}
+
+type deadLetter struct {
+	DeliveryID string `json:"delivery_id"`
+	WebhookID  string `json:"webhook_id"`
+	URL        string `json:"url"`
+	EventID    string `json:"event_id"`
+	EventType  string `json:"event_type"`
+	Payload    string `json:"payload"`
+	Attempts   int    `json:"attempts"`
+	LastError  string `json:"last_error"`
+	FailedAt   string `json:"failed_at"`
+}
+
+func (d *WebhookDispatcher) deadLetter(ctx context.Context, hook *db.Webhook, delivery *db.WebhookDelivery) error {
+	if d.rdb == nil {
+		// Without Redis the delivery is only marked dead in the database.
+		return nil
+	}
+	entry, err := json.Marshal(deadLetter{
+		DeliveryID: delivery.ID,
+		WebhookID:  hook.ID,
+		URL:        hook.URL,
+		EventID:    delivery.EventID,
+		EventType:  delivery.EventType,
+		Payload:    delivery.Payload,
+		Attempts:   delivery.Attempts,
+		LastError:  delivery.LastError,
+		FailedAt:   time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	pipe := d.rdb.TxPipeline()
+	pipe.LPush(ctx, webhookDeadLetterKey, entry)
+	pipe.LTrim(ctx, webhookDeadLetterKey, 0, webhookDeadLetterRetained-1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (d *WebhookDispatcher) newDeliveryID() string {
+	if d.IDs == nil {
+		return "delivery-" + idgen.Default.NewID()
+	}
+	return "delivery-" + d.IDs.NewID()
+}
+
+type WebhookServer struct {
+	blog.UnimplementedWebhookServiceServer
+	*Server
+	// AllowPrivateTargets is WebhookDispatcher.AllowPrivateTargets for
+	// creating webhooks.
+	AllowPrivateTargets bool
+}
+
+func NewWebhookServer(s *Server) *WebhookServer {
+	return &WebhookServer{Server: s}
+}
+
+func dbWebhookToProtoWebhook(hook *db.Webhook) *blog.Webhook {
+	webhook := &blog.Webhook{
+		Id:        hook.ID,
+		Url:       hook.URL,
+		CreatedAt: hook.CreatedAt.Format("15:04:05 02.01.2006"),
+	}
+	for _, name := range strings.Split(hook.EventTypes, ",") {
+		webhook.EventTypes = append(webhook.EventTypes, blog.FeedEvent_Type(blog.FeedEvent_Type_value[name]))
+	}
+	return webhook
+}
+
+func dbDeliveryToProtoDelivery(delivery *db.WebhookDelivery) *blog.Delivery {
+	return &blog.Delivery{
+		Id:           delivery.ID,
+		WebhookId:    delivery.WebhookID,
+		EventId:      delivery.EventID,
+		EventType:    blog.FeedEvent_Type(blog.FeedEvent_Type_value[delivery.EventType]),
+		Status:       deliveryStatuses[delivery.Status],
+		Attempts:     int32(delivery.Attempts),
+		ResponseCode: int32(delivery.ResponseCode),
+		LastError:    delivery.LastError,
+		CreatedAt:    delivery.CreatedAt.Format("15:04:05 02.01.2006"),
+		UpdatedAt:    delivery.UpdatedAt.Format("15:04:05 02.01.2006"),
+	}
+}
+
+func (s *WebhookServer) CreateWebhook(ctx context.Context, req *blog.CreateWebhookRequest) (*blog.Webhook, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(req.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "url must be an absolute http or https url, got %q", req.Url)
+	}
+	if err := s.checkTarget(ctx, target.Hostname()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid url %q: %v", req.Url, err)
+	}
+	if req.Secret == "" {
+		return nil, status.Error(codes.InvalidArgument, "secret is required")
+	}
+	if len(req.EventTypes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one event type is required")
+	}
+
+	var names []string
+	seen := make(map[blog.FeedEvent_Type]bool)
+	for _, eventType := range req.EventTypes {
+		if !webhookEventTypes[eventType] {
+			return nil, status.Errorf(codes.InvalidArgument, "can't subscribe to %s events", eventType)
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			names = append(names, eventType.String())
+		}
+	}
+
+	hook := db.Webhook{
+		ID:         s.newID("webhook-"),
+		OwnerID:    userID,
+		URL:        req.Url,
+		EventTypes: strings.Join(names, ","),
+		Secret:     req.Secret,
+		CreatedAt:  time.Now(),
+	}
+	subscriptions := make([]db.WebhookEventType, len(names))
+	for i, name := range names {
+		subscriptions[i] = db.WebhookEventType{EventType: name, WebhookID: hook.ID}
+	}
+	err = s.Sql_DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&hook).Error; err != nil {
+			return err
+		}
+		return tx.Create(&subscriptions).Error
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create webhook: %v", err)
+	}
+
+	return dbWebhookToProtoWebhook(&hook), nil
+}
+
+// checkTarget refuses hosts that are or resolve to addresses webhooks must
+// not reach.
+func (s *WebhookServer) checkTarget(ctx context.Context, host string) error {
+	if s.AllowPrivateTargets {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkWebhookAddr(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("can't resolve %s: %v", host, err)
+	}
+	for _, addr := range addrs {
+		if err := checkWebhookAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *WebhookServer) ListWebhooks(ctx context.Context, req *blog.ListWebhooksRequest) (*blog.ListWebhooksResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []db.Webhook
+	if err := s.Sql_DB.WithContext(ctx).Where("owner_id = ?", userID).Order("created_at").Find(&hooks).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get webhooks: %v", err)
+	}
+
+	resp := &blog.ListWebhooksResponse{}
+	for i := range hooks {
+		resp.Webhooks = append(resp.Webhooks, dbWebhookToProtoWebhook(&hooks[i]))
+	}
+	return resp, nil
+}
+
+// ownWebhook loads a webhook of the caller. Other users' webhooks are
+// reported as missing so their ids can't be probed.
+func (s *WebhookServer) ownWebhook(ctx context.Context, userID, id string) (*db.Webhook, error) {
+	var hook db.Webhook
+	err := s.Sql_DB.WithContext(ctx).First(&hook, "id = ? AND owner_id = ?", id, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Error(codes.NotFound, "webhook not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get webhook: %v", err)
+	}
+	return &hook, nil
+}
+
+func (s *WebhookServer) DeleteWebhook(ctx context.Context, req *blog.DeleteWebhookRequest) (*blog.DeleteWebhookResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hook, err := s.ownWebhook(ctx, userID, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Sql_DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", hook.ID).Delete(&db.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("webhook_id = ?", hook.ID).Delete(&db.WebhookEventType{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(hook).Error
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete webhook: %v", err)
+	}
+
+	return &blog.DeleteWebhookResponse{}, nil
+}
+
+func (s *WebhookServer) ListDeliveries(ctx context.Context, req *blog.ListDeliveriesRequest) (*blog.ListDeliveriesResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.ownWebhook(ctx, userID, req.WebhookId); err != nil {
+		return nil, err
+	}
+
+	start, stop := listWindow(req.Limit, req.Offset)
+	var deliveries []db.WebhookDelivery
+	err = s.Sql_DB.WithContext(ctx).
+		Where("webhook_id = ?", req.WebhookId).
+		Order("created_at DESC, id DESC").
+		Offset(int(start)).Limit(int(stop - start + 1)).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get deliveries: %v", err)
+	}
+
+	resp := &blog.ListDeliveriesResponse{}
+	for i := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, dbDeliveryToProtoDelivery(&deliveries[i]))
+	}
+	return resp, nil
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	blog "go_grpc_blog/api"
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestWebhookSendSignsPayload(t *testing.T) {
+	var got *http.Request
+	var body string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got, body = r, string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	d := NewWebhookDispatcher(nil, nil)
+	d.AllowPrivateTargets = true
+	hook := &db.Webhook{URL: target.URL, Secret: "s3cret"}
+	delivery := &db.WebhookDelivery{ID: "delivery-1", EventType: "POST_CREATED", Payload: `{"type":"POST_CREATED"}`}
+
+	code, err := d.send(context.Background(), hook, delivery)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, code)
+
+	require.Equal(t, delivery.Payload, body)
+	require.Equal(t, "post_created", got.Header.Get("X-Blog-Event"))
+	require.Equal(t, "delivery-1", got.Header.Get("X-Blog-Delivery"))
+	want := "sha256=" + signPayload("s3cret", got.Header.Get("X-Blog-Timestamp"), body)
+	require.Equal(t, want, got.Header.Get("X-Blog-Signature"))
+}
+
+func TestWebhookSendFailsOnErrorStatus(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer target.Close()
+
+	d := NewWebhookDispatcher(nil, nil)
+	d.AllowPrivateTargets = true
+	code, err := d.send(context.Background(), &db.Webhook{URL: target.URL}, &db.WebhookDelivery{})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadGateway, code)
+}
+
+func TestWebhookSendRefusesPrivateTargets(t *testing.T) {
+	called := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer target.Close()
+
+	d := NewWebhookDispatcher(nil, nil)
+	_, err := d.send(context.Background(), &db.Webhook{URL: target.URL}, &db.WebhookDelivery{})
+	require.ErrorContains(t, err, "webhooks can't target 127.0.0.1")
+	require.False(t, called)
+}
+
+func TestCreateWebhookRefusesPrivateTargets(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("user-id", "user-1"))
+	s := NewWebhookServer(&Server{})
+	for _, target := range []string{
+		"http://127.0.0.1:8090/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.1.2.3/hook",
+		"https://192.168.0.10/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		_, err := s.CreateWebhook(ctx, &blog.CreateWebhookRequest{
+			Url:        target,
+			Secret:     "s3cret",
+			EventTypes: []blog.FeedEvent_Type{blog.FeedEvent_POST_CREATED},
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), target)
+	}
+
+	require.NoError(t, checkWebhookAddr(netip.MustParseAddr("93.184.216.34")))
+}
+
+func TestWebhookRetriesWithBackoffThenDies(t *testing.T) {
+	d := NewWebhookDispatcher(nil, nil)
+	d.MaxAttempts = 4
+	now := time.Now()
+	delivery := &db.WebhookDelivery{Status: deliveryPending}
+
+	for _, wait := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second} {
+		require.False(t, d.recordAttempt(delivery, 500, errors.New("boom"), now))
+		require.Equal(t, deliveryPending, delivery.Status)
+		require.Equal(t, now.Add(wait), delivery.NextAttemptAt)
+	}
+	require.True(t, d.recordAttempt(delivery, 0, errors.New("boom"), now))
+	require.Equal(t, deliveryDead, delivery.Status)
+	require.Equal(t, 4, delivery.Attempts)
+
+	require.Equal(t, d.MaxBackoff, d.backoff(30))
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+	d := NewWebhookDispatcher(nil, rdb)
+
+	hook := &db.Webhook{ID: "webhook-1", URL: "https://example.com/hook"}
+	delivery := &db.WebhookDelivery{ID: "delivery-1", Attempts: 8, LastError: "boom"}
+	require.NoError(t, d.deadLetter(ctx, hook, delivery))
+
+	entries, err := rdb.LRange(ctx, webhookDeadLetterKey, 0, -1).Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	var entry deadLetter
+	require.NoError(t, json.Unmarshal([]byte(entries[0]), &entry))
+	require.Equal(t, "delivery-1", entry.DeliveryID)
+	require.Equal(t, "https://example.com/hook", entry.URL)
+	require.Equal(t, "boom", entry.LastError)
+}
+
+func TestEnqueueOnlyForSubscribedWebhooks(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("user-id", "user-1"))
+	sqlDB := newTestDatabase(t)
+	s := NewWebhookServer(&Server{Sql_DB: sqlDB})
+
+	posts, err := s.CreateWebhook(ctx, &blog.CreateWebhookRequest{
+		Url:        "https://93.184.216.34/posts",
+		Secret:     "s3cret",
+		EventTypes: []blog.FeedEvent_Type{blog.FeedEvent_POST_CREATED, blog.FeedEvent_POST_DELETED},
+	})
+	require.NoError(t, err)
+	likes, err := s.CreateWebhook(ctx, &blog.CreateWebhookRequest{
+		Url:        "https://93.184.216.34/likes",
+		Secret:     "s3cret",
+		EventTypes: []blog.FeedEvent_Type{blog.FeedEvent_LIKES_CHANGED},
+	})
+	require.NoError(t, err)
+
+	d := NewWebhookDispatcher(sqlDB, nil)
+	require.NoError(t, d.Enqueue(ctx, &blog.FeedEvent{Id: "event-1", Type: blog.FeedEvent_POST_CREATED}))
+	require.NoError(t, d.Enqueue(ctx, &blog.FeedEvent{Id: "event-2", Type: blog.FeedEvent_POST_UPDATED}))
+
+	var deliveries []db.WebhookDelivery
+	require.NoError(t, sqlDB.Find(&deliveries).Error)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, posts.Id, deliveries[0].WebhookID)
+	require.Equal(t, "event-1", deliveries[0].EventID)
+
+	_, err = s.DeleteWebhook(ctx, &blog.DeleteWebhookRequest{Id: likes.Id})
+	require.NoError(t, err)
+	var subscriptions int64
+	require.NoError(t, sqlDB.Model(&db.WebhookEventType{}).Count(&subscriptions).Error)
+	require.EqualValues(t, 2, subscriptions)
+}
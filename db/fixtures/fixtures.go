@@ -0,0 +1,270 @@
+// Package fixtures seeds a database with users, posts and likes read from
+// YAML or JSON files. Named sets are built in:
+//
+//	demo  the anime characters the blog has always shown
+//	test  a few users, posts and likes for tests
+//	load  a larger set for trying the server under some load
+//
+// Anything else is read from disk, either a single .yaml, .yml or .json
+// file or a directory of them merged together.
+//
+// Seeding upserts: rows are matched by id, or by post and user for likes,
+// and seeding the same fixtures again leaves the database as it was.
+package fixtures
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:embed sets
+var sets embed.FS
+
+// User is a fixture user.
+type User struct {
+	ID       string `yaml:"id" json:"id"`
+	NickName string `yaml:"nick_name" json:"nick_name"`
+	PhotoURL string `yaml:"photo_url" json:"photo_url"`
+}
+
+// Post is a fixture post, Author is the id of its author.
+type Post struct {
+	ID        string    `yaml:"id" json:"id"`
+	Author    string    `yaml:"author" json:"author"`
+	Body      string    `yaml:"body" json:"body"`
+	CreatedAt time.Time `yaml:"created_at" json:"created_at"`
+}
+
+// Like is a fixture like. Without a CreatedAt the post was liked when it
+// was created.
+type Like struct {
+	Post      string    `yaml:"post" json:"post"`
+	User      string    `yaml:"user" json:"user"`
+	CreatedAt time.Time `yaml:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// Fixtures are the rows of one or more fixture files.
+type Fixtures struct {
+	Users []User `yaml:"users" json:"users"`
+	Posts []Post `yaml:"posts" json:"posts"`
+	Likes []Like `yaml:"likes" json:"likes"`
+}
+
+// Sets returns the names of the built-in sets.
+func Sets() []string {
+	entries, _ := fs.ReadDir(sets, "sets")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+	}
+	return names
+}
+
+// Load reads a built-in set by name, or else the file or directory at
+// nameOrPath, and validates it.
+func Load(nameOrPath string) (*Fixtures, error) {
+	f, err := load(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("invalid fixtures %s: %w", nameOrPath, err)
+	}
+	return f, nil
+}
+
+func load(nameOrPath string) (*Fixtures, error) {
+	for _, ext := range []string{".yaml", ".json"} {
+		data, err := sets.ReadFile("sets/" + nameOrPath + ext)
+		if err == nil {
+			return decode(nameOrPath+ext, data)
+		}
+	}
+
+	info, err := os.Stat(nameOrPath)
+	if err != nil {
+		return nil, fmt.Errorf("no fixture set or file %q, the built-in sets are %s", nameOrPath, strings.Join(Sets(), ", "))
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(nameOrPath)
+		if err != nil {
+			return nil, err
+		}
+		return decode(nameOrPath, data)
+	}
+
+	entries, err := os.ReadDir(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+	merged := &Fixtures{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isFixtureFile(entry.Name()) {
+			continue
+		}
+		file := filepath.Join(nameOrPath, entry.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		f, err := decode(file, data)
+		if err != nil {
+			return nil, err
+		}
+		merged.Users = append(merged.Users, f.Users...)
+		merged.Posts = append(merged.Posts, f.Posts...)
+		merged.Likes = append(merged.Likes, f.Likes...)
+	}
+	return merged, nil
+}
+
+func isFixtureFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func decode(file string, data []byte) (*Fixtures, error) {
+	f := &Fixtures{}
+	var err error
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, f)
+	case ".json":
+		err = json.Unmarshal(data, f)
+	default:
+		return nil, fmt.Errorf("unknown fixture format %s, want .yaml, .yml or .json", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", file, err)
+	}
+	return f, nil
+}
+
+// validate checks that ids are unique and that posts and likes only refer
+// to users and posts of the same fixtures.
+func (f *Fixtures) validate() error {
+	users := make(map[string]bool, len(f.Users))
+	nickNames := make(map[string]bool, len(f.Users))
+	for _, u := range f.Users {
+		if u.ID == "" || u.NickName == "" {
+			return fmt.Errorf("user %q needs an id and a nick_name", u.ID)
+		}
+		if users[u.ID] || nickNames[u.NickName] {
+			return fmt.Errorf("user %s or nick name %s is listed twice", u.ID, u.NickName)
+		}
+		users[u.ID] = true
+		nickNames[u.NickName] = true
+	}
+
+	posts := make(map[string]time.Time, len(f.Posts))
+	for _, p := range f.Posts {
+		if p.ID == "" || p.Body == "" || p.CreatedAt.IsZero() {
+			return fmt.Errorf("post %q needs an id, a body and a created_at", p.ID)
+		}
+		if _, ok := posts[p.ID]; ok {
+			return fmt.Errorf("post %s is listed twice", p.ID)
+		}
+		if !users[p.Author] {
+			return fmt.Errorf("post %s is by unknown user %q", p.ID, p.Author)
+		}
+		posts[p.ID] = p.CreatedAt
+	}
+
+	liked := make(map[[2]string]bool, len(f.Likes))
+	for i, l := range f.Likes {
+		created, ok := posts[l.Post]
+		if !ok {
+			return fmt.Errorf("like of unknown post %q", l.Post)
+		}
+		if !users[l.User] {
+			return fmt.Errorf("like of post %s by unknown user %q", l.Post, l.User)
+		}
+		if liked[[2]string{l.Post, l.User}] {
+			return fmt.Errorf("like of post %s by %s is listed twice", l.Post, l.User)
+		}
+		liked[[2]string{l.Post, l.User}] = true
+		if l.CreatedAt.IsZero() {
+			f.Likes[i].CreatedAt = created
+		}
+	}
+	return nil
+}
+
+// PostIDs returns the ids of the fixture posts.
+func (f *Fixtures) PostIDs() []string {
+	ids := make([]string, len(f.Posts))
+	for i, p := range f.Posts {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+// Models returns the fixtures as database rows.
+func (f *Fixtures) Models() ([]db.User, []db.Post, []db.Like) {
+	users := make([]db.User, len(f.Users))
+	for i, u := range f.Users {
+		users[i] = db.User{ID: u.ID, NickName: u.NickName, PhotoURL: u.PhotoURL}
+	}
+	posts := make([]db.Post, len(f.Posts))
+	for i, p := range f.Posts {
+		posts[i] = db.Post{ID: p.ID, AuthorID: p.Author, Body: p.Body, CreatedAt: p.CreatedAt.UTC()}
+	}
+	likes := make([]db.Like, len(f.Likes))
+	for i, l := range f.Likes {
+		likes[i] = db.Like{PostID: l.Post, UserID: l.User, CreatedAt: l.CreatedAt.UTC()}
+	}
+	return users, posts, likes
+}
+
+const seedBatchSize = 500
+
+// Seed upserts the fixtures in one transaction.
+func Seed(ctx context.Context, sqlDB *gorm.DB, f *Fixtures) error {
+	users, posts, likes := f.Models()
+	return sqlDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(users) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"nick_name", "photo_url"}),
+			}).CreateInBatches(users, seedBatchSize).Error
+			if err != nil {
+				return fmt.Errorf("failed to seed users: %w", err)
+			}
+		}
+		if len(posts) > 0 {
+			err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"author_id", "body", "created_at"}),
+			}).CreateInBatches(posts, seedBatchSize).Error
+			if err != nil {
+				return fmt.Errorf("failed to seed posts: %w", err)
+			}
+		}
+		if len(likes) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "post_id"}, {Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"created_at"}),
+			}).CreateInBatches(likes, seedBatchSize).Error
+			if err != nil {
+				return fmt.Errorf("failed to seed likes: %w", err)
+			}
+		}
+		return nil
+	})
+}
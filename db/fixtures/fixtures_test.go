@@ -0,0 +1,83 @@
+package fixtures
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltInSets(t *testing.T) {
+	require.ElementsMatch(t, []string{"demo", "load", "test"}, Sets())
+	for _, name := range Sets() {
+		f, err := Load(name)
+		require.NoError(t, err, name)
+		require.NotEmpty(t, f.Users, name)
+		require.NotEmpty(t, f.Posts, name)
+		require.NotEmpty(t, f.Likes, name)
+	}
+
+	_, err := Load("nope")
+	require.Error(t, err)
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users.yaml"), []byte(`
+users:
+  - id: user-1
+    nick_name: naruto_uzumaki
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "posts.json"), []byte(`{
+  "posts": [{"id": "post-1", "author": "user-1", "body": "Hi", "created_at": "2025-03-26T13:11:00Z"}],
+  "likes": [{"post": "post-1", "user": "user-1"}]
+}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a fixture"), 0o644))
+
+	f, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, f.Users, 1)
+	require.Len(t, f.Posts, 1)
+	// Likes default to the time of the post.
+	require.Equal(t, f.Posts[0].CreatedAt, f.Likes[0].CreatedAt)
+
+	_, err = Load(filepath.Join(dir, "posts.json"))
+	require.ErrorContains(t, err, "unknown user")
+}
+
+func TestSeedIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, err := db.InitDB("sqlite://" + filepath.Join(t.TempDir(), "blog.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if conn, err := sqlDB.DB(); err == nil {
+			conn.Close()
+		}
+	})
+
+	f, err := Load("test")
+	require.NoError(t, err)
+	require.NoError(t, Seed(ctx, sqlDB, f))
+
+	f.Posts[0].Body = "Edited"
+	require.NoError(t, Seed(ctx, sqlDB, f))
+
+	var users, posts, likes int64
+	require.NoError(t, sqlDB.Model(&db.User{}).Count(&users).Error)
+	require.NoError(t, sqlDB.Model(&db.Post{}).Count(&posts).Error)
+	require.NoError(t, sqlDB.Model(&db.Like{}).Count(&likes).Error)
+	require.EqualValues(t, len(f.Users), users)
+	require.EqualValues(t, len(f.Posts), posts)
+	require.EqualValues(t, len(f.Likes), likes)
+
+	var post db.Post
+	require.NoError(t, sqlDB.Preload("Author").First(&post, "id = ?", f.Posts[0].ID).Error)
+	require.Equal(t, "Edited", post.Body)
+	require.Equal(t, "test_author", post.Author.NickName)
+	require.True(t, post.CreatedAt.Equal(time.Date(2025, 3, 26, 13, 0, 0, 0, time.UTC)))
+}
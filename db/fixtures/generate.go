@@ -0,0 +1,193 @@
+package fixtures
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenerateConfig describes a synthetic data set for load testing.
+type GenerateConfig struct {
+	Users int
+	Posts int
+	// Likes is roughly how many likes there are in total, but no post has
+	// more likes than there are users.
+	Likes int
+	// Posts are created between From and To, likes after their post,
+	// mostly within a day, and never after To.
+	From, To time.Time
+	// AuthorSkew and LikeSkew are the exponents of the power laws, above 1,
+	// that users post and posts are liked by: the n-th most active author
+	// writes about n^-AuthorSkew of the posts, the n-th most popular post
+	// gets about n^-LikeSkew of the likes. Likers are skewed by LikeSkew too.
+	AuthorSkew float64
+	LikeSkew   float64
+	// Seed makes runs reproducible: the same config generates the same data.
+	Seed int64
+	// BatchSize is how many users or posts, with their likes, are handed
+	// over at a time. It doesn't change what is generated.
+	BatchSize int
+}
+
+func (c GenerateConfig) validate() error {
+	switch {
+	case c.Users < 1 || c.Posts < 0 || c.Likes < 0:
+		return fmt.Errorf("need at least one user and no negative number of posts or likes")
+	case !c.To.After(c.From):
+		return fmt.Errorf("time range %s to %s is empty", c.From.Format(time.RFC3339), c.To.Format(time.RFC3339))
+	case c.AuthorSkew <= 1 || c.LikeSkew <= 1:
+		return fmt.Errorf("skews must be above 1")
+	case c.BatchSize < 1:
+		return fmt.Errorf("batch size must be positive")
+	}
+	return nil
+}
+
+const (
+	generatedUserPrefix = "gen-user-"
+	generatedPostPrefix = "gen-post-"
+)
+
+// GeneratedUserID is the id of the n-th generated user, counting from 0.
+func GeneratedUserID(n int) string { return generatedUserPrefix + strconv.Itoa(n) }
+
+// GeneratedPostID is the id of the n-th generated post, counting from 0.
+func GeneratedPostID(n int) string { return generatedPostPrefix + strconv.Itoa(n) }
+
+var words = strings.Fields(`the a feed post like anime ninja hero blade titan ghoul
+	spirit power friend rival train fight win lose again today tomorrow never always
+	really so much more than ever before after episode season finale plot twist`)
+
+// Generate generates users, then posts with their likes, and passes them to
+// emit in batches. Everything is generated on the fly, so millions of rows
+// only take memory for the posts' popularity.
+func Generate(cfg GenerateConfig, emit func(*Fixtures) error) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	r := rand.New(rand.NewSource(cfg.Seed))
+
+	batch := &Fixtures{}
+	for n := 0; n < cfg.Users; n++ {
+		batch.Users = append(batch.Users, User{
+			ID:       GeneratedUserID(n),
+			NickName: fmt.Sprintf("gen_user_%d", n),
+			PhotoURL: fmt.Sprintf("https://gen-user-%d.jpg", n),
+		})
+		if len(batch.Users) == cfg.BatchSize {
+			if err := emit(batch); err != nil {
+				return err
+			}
+			batch = &Fixtures{}
+		}
+	}
+	if len(batch.Users) > 0 {
+		if err := emit(batch); err != nil {
+			return err
+		}
+		batch = &Fixtures{}
+	}
+
+	// Ranks are shuffled so that activity and popularity don't follow ids.
+	authorByRank := r.Perm(cfg.Users)
+	likerByRank := r.Perm(cfg.Users)
+	authorRank := rand.NewZipf(r, cfg.AuthorSkew, 1, uint64(cfg.Users-1))
+	likerRank := rand.NewZipf(r, cfg.LikeSkew, 1, uint64(cfg.Users-1))
+	popularity := r.Perm(cfg.Posts)
+	var totalWeight float64
+	for rank := 0; rank < cfg.Posts; rank++ {
+		totalWeight += math.Pow(float64(rank+1), -cfg.LikeSkew)
+	}
+	// The most popular posts can't be liked by more than every user, what
+	// they would get beyond that goes to the others.
+	capped, rest := 0, float64(cfg.Likes)
+	for capped < cfg.Posts {
+		weight := math.Pow(float64(capped+1), -cfg.LikeSkew)
+		if rest*weight/totalWeight < float64(cfg.Users) {
+			break
+		}
+		rest -= float64(cfg.Users)
+		totalWeight -= weight
+		capped++
+	}
+
+	span := cfg.To.Sub(cfg.From)
+	for n := 0; n < cfg.Posts; n++ {
+		author := authorByRank[authorRank.Uint64()]
+		post := Post{
+			ID:        GeneratedPostID(n),
+			Author:    GeneratedUserID(author),
+			Body:      body(r),
+			CreatedAt: cfg.From.Add(time.Duration(r.Int63n(int64(span)))).UTC().Truncate(time.Millisecond),
+		}
+		batch.Posts = append(batch.Posts, post)
+
+		expected := float64(cfg.Users)
+		if popularity[n] >= capped {
+			expected = rest * math.Pow(float64(popularity[n]+1), -cfg.LikeSkew) / totalWeight
+		}
+		count := int(expected)
+		if r.Float64() < expected-float64(count) {
+			count++
+		}
+		for _, liker := range likers(r, likerRank, likerByRank, min(count, cfg.Users)) {
+			delay := time.Duration(r.ExpFloat64() * float64(24*time.Hour))
+			at := post.CreatedAt.Add(delay)
+			if at.After(cfg.To) {
+				at = cfg.To
+			}
+			batch.Likes = append(batch.Likes, Like{Post: post.ID, User: GeneratedUserID(liker), CreatedAt: at.UTC().Truncate(time.Millisecond)})
+		}
+
+		if len(batch.Posts) == cfg.BatchSize {
+			if err := emit(batch); err != nil {
+				return err
+			}
+			batch = &Fixtures{}
+		}
+	}
+	if len(batch.Posts) > 0 {
+		return emit(batch)
+	}
+	return nil
+}
+
+// likers picks count distinct users, the more active ones more likely. Once
+// the power law keeps hitting users already picked, the rest are picked
+// uniformly.
+func likers(r *rand.Rand, rank *rand.Zipf, byRank []int, count int) []int {
+	users := len(byRank)
+	if count*2 > users {
+		return r.Perm(users)[:count]
+	}
+
+	picked := make(map[int]bool, count)
+	result := make([]int, 0, count)
+	for tries := 0; len(result) < count && tries < 4*count; tries++ {
+		user := byRank[rank.Uint64()]
+		if !picked[user] {
+			picked[user] = true
+			result = append(result, user)
+		}
+	}
+	for len(result) < count {
+		user := r.Intn(users)
+		if !picked[user] {
+			picked[user] = true
+			result = append(result, user)
+		}
+	}
+	return result
+}
+
+func body(r *rand.Rand) string {
+	n := 3 + r.Intn(30)
+	b := make([]string, n)
+	for i := range b {
+		b[i] = words[r.Intn(len(words))]
+	}
+	return strings.ToUpper(b[0][:1]) + strings.Join(b, " ")[1:] + "!"
+}
@@ -0,0 +1,145 @@
+package fixtures
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"go_grpc_blog/db"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testGenerateConfig = GenerateConfig{
+	Users:      1000,
+	Posts:      5000,
+	Likes:      20000,
+	From:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	To:         time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	AuthorSkew: 1.2,
+	LikeSkew:   1.2,
+	Seed:       42,
+	BatchSize:  700,
+}
+
+func generateAll(t *testing.T, cfg GenerateConfig) *Fixtures {
+	all := &Fixtures{}
+	require.NoError(t, Generate(cfg, func(f *Fixtures) error {
+		require.LessOrEqual(t, len(f.Users)+len(f.Posts), cfg.BatchSize)
+		all.Users = append(all.Users, f.Users...)
+		all.Posts = append(all.Posts, f.Posts...)
+		all.Likes = append(all.Likes, f.Likes...)
+		return nil
+	}))
+	return all
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	cfg := testGenerateConfig
+	first := generateAll(t, cfg)
+	require.Equal(t, first, generateAll(t, cfg))
+
+	cfg.BatchSize = 64
+	require.Equal(t, first, generateAll(t, cfg))
+
+	cfg.Seed++
+	require.NotEqual(t, first, generateAll(t, cfg))
+
+	cfg.AuthorSkew = 1
+	require.Error(t, Generate(cfg, func(*Fixtures) error { return nil }))
+}
+
+func TestGenerateFollowsPowerLaws(t *testing.T) {
+	cfg := testGenerateConfig
+	f := generateAll(t, cfg)
+	require.Len(t, f.Users, cfg.Users)
+	require.Len(t, f.Posts, cfg.Posts)
+	require.InDelta(t, cfg.Likes, len(f.Likes), float64(cfg.Likes)/10)
+	require.NoError(t, f.validate())
+
+	created := make(map[string]time.Time, len(f.Posts))
+	postsByAuthor := make(map[string]int)
+	for _, p := range f.Posts {
+		require.False(t, p.CreatedAt.Before(cfg.From), p.ID)
+		require.True(t, p.CreatedAt.Before(cfg.To), p.ID)
+		created[p.ID] = p.CreatedAt
+		postsByAuthor[p.Author]++
+	}
+	likesByPost := make(map[string]int)
+	for _, l := range f.Likes {
+		require.False(t, l.CreatedAt.Before(created[l.Post]))
+		require.False(t, l.CreatedAt.After(cfg.To))
+		likesByPost[l.Post]++
+	}
+
+	// A few authors write most posts, a few posts get most likes.
+	require.Greater(t, top(postsByAuthor, cfg.Users/100), cfg.Posts/2)
+	require.Greater(t, top(likesByPost, cfg.Posts/100), cfg.Likes/2)
+	require.Less(t, len(postsByAuthor), cfg.Users)
+}
+
+// top sums the largest n counts.
+func top(counts map[string]int, n int) int {
+	values := make([]int, 0, len(counts))
+	for _, v := range counts {
+		values = append(values, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(values)))
+	sum := 0
+	for _, v := range values[:min(n, len(values))] {
+		sum += v
+	}
+	return sum
+}
+
+func TestInsertGenerated(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, err := db.InitDB("sqlite://" + filepath.Join(t.TempDir(), "blog.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if conn, err := sqlDB.DB(); err == nil {
+			conn.Close()
+		}
+	})
+
+	cfg := testGenerateConfig
+	cfg.Users, cfg.Posts, cfg.Likes = 50, 200, 600
+	f := generateAll(t, cfg)
+	require.NoError(t, Generate(cfg, func(batch *Fixtures) error {
+		return Insert(ctx, sqlDB, batch)
+	}))
+
+	var users, posts, likes int64
+	require.NoError(t, sqlDB.Model(&db.User{}).Count(&users).Error)
+	require.NoError(t, sqlDB.Model(&db.Post{}).Count(&posts).Error)
+	require.NoError(t, sqlDB.Model(&db.Like{}).Count(&likes).Error)
+	require.EqualValues(t, len(f.Users), users)
+	require.EqualValues(t, len(f.Posts), posts)
+	require.EqualValues(t, len(f.Likes), likes)
+
+	// Inserting again fails rather than duplicating.
+	require.Error(t, Generate(cfg, func(batch *Fixtures) error {
+		return Insert(ctx, sqlDB, batch)
+	}))
+
+	// Deleting leaves other data alone, except for likes by generated users.
+	require.NoError(t, sqlDB.Create(&db.User{ID: "user-1", NickName: "naruto_uzumaki"}).Error)
+	require.NoError(t, sqlDB.Create(&db.Post{ID: "post-1", AuthorID: "user-1", Body: "Post 1"}).Error)
+	require.NoError(t, sqlDB.Create(&db.Like{PostID: "post-1", UserID: GeneratedUserID(0)}).Error)
+	deleted, err := DeleteGenerated(ctx, sqlDB)
+	require.NoError(t, err)
+	require.Len(t, deleted, len(f.Posts))
+
+	require.NoError(t, sqlDB.Model(&db.User{}).Count(&users).Error)
+	require.NoError(t, sqlDB.Model(&db.Post{}).Count(&posts).Error)
+	require.NoError(t, sqlDB.Model(&db.Like{}).Count(&likes).Error)
+	require.EqualValues(t, 1, users)
+	require.EqualValues(t, 1, posts)
+	require.Zero(t, likes)
+
+	require.NoError(t, Generate(cfg, func(batch *Fixtures) error {
+		return Insert(ctx, sqlDB, batch)
+	}))
+}
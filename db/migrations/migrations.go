@@ -0,0 +1,391 @@
+// Package migrations versions the SQL schema. Every dialect has its own
+// directory of migrations named <version>_<name>.up.sql and .down.sql, e.g.
+// postgres/0002_add_post_title.up.sql, applied in version order and recorded
+// in schema_migrations with the checksum of their up script.
+//
+// Statements in a script are separated by a semicolon at the end of a line.
+// A migration and its record are applied in one transaction, except on
+// MySQL, which commits every DDL statement on its own: a MySQL migration
+// failing halfway has to be cleaned up by hand.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed postgres sqlite mysql
+var files embed.FS
+
+// Migration is one schema change.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Status is a migration together with whether it was applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// ErrChecksumMismatch is returned when an applied migration was edited.
+var ErrChecksumMismatch = errors.New("applied migration was changed")
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load returns the migrations of a dialect in version order.
+func Load(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s", dialect)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s/%s", dialect, entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		script, err := files.ReadFile(path.Join(dialect, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d of %s is named both %s and %s", version, dialect, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(script)
+			sum := sha256.Sum256(script)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %s of %s needs both an up and a down script", m, dialect)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// statements splits a script into its statements.
+func statements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		stmts = append(stmts, strings.TrimSpace(current.String()))
+	}
+	return stmts
+}
+
+// appliedMigration is a row of schema_migrations.
+type appliedMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string { return "schema_migrations" }
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    name VARCHAR(191) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`
+
+// Migrator applies the migrations of a database's dialect.
+type Migrator struct {
+	sqlDB      *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+func New(sqlDB *gorm.DB) (*Migrator, error) {
+	dialect := sqlDB.Dialector.Name()
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{sqlDB: sqlDB, dialect: dialect, migrations: migrations}, nil
+}
+
+// Up applies every pending migration and returns those it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		// Newer migrations are left alone, they are expected to keep the
+		// schema working for the version that was rolled out before.
+		done, _, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			ok, err := m.apply(conn, migration)
+			if err != nil {
+				return err
+			}
+			if ok {
+				applied = append(applied, migration)
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns those it
+// reverted, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		done, newer, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		if len(newer) > 0 {
+			return fmt.Errorf("migration %04d_%s was applied by a newer version, revert it with that one", newer[len(newer)-1].Version, newer[len(newer)-1].Name)
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(conn, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns every migration in version order, including those applied
+// by a newer version, which have no scripts.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn := m.sqlDB.WithContext(ctx)
+	if err := conn.Exec(createSchemaMigrations).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	done, newer, err := m.applied(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations)+len(newer))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if row, ok := done[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range newer {
+		statuses = append(statuses, Status{
+			Migration: Migration{Version: row.Version, Name: row.Name, Checksum: row.Checksum},
+			Applied:   true,
+			AppliedAt: row.AppliedAt,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// applied returns the applied migrations, after checking that those this
+// binary knows weren't changed. Migrations it doesn't know were applied by a
+// newer version and are returned apart.
+func (m *Migrator) applied(conn *gorm.DB) (known map[int64]appliedMigration, newer []appliedMigration, err error) {
+	var rows []appliedMigration
+	if err := conn.Order("version").Find(&rows).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = migration
+	}
+	known = make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		migration, ok := byVersion[row.Version]
+		if !ok {
+			newer = append(newer, row)
+			continue
+		}
+		if migration.Checksum != row.Checksum {
+			return nil, nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, migration)
+		}
+		known[row.Version] = row
+	}
+	return known, newer, nil
+}
+
+// apply applies a migration unless it was applied meanwhile, which only
+// happens on SQLite.
+func (m *Migrator) apply(conn *gorm.DB, migration Migration) (bool, error) {
+	applied := false
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&appliedMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		applied = true
+		for _, stmt := range statements(migration.Up) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&appliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to apply migration %s: %w", migration, err)
+	}
+	return applied, nil
+}
+
+func (m *Migrator) revert(conn *gorm.DB, migration Migration) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range statements(migration.Down) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&appliedMigration{Version: migration.Version}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revert migration %s: %w", migration, err)
+	}
+	return nil
+}
+
+// lockKey identifies the migration lock among the database's advisory locks.
+const (
+	lockKey  = 0x626c6f676d6967 // "blogmig"
+	lockName = "go_grpc_blog.migrations"
+)
+
+// locked runs fn on a single connection holding the migration lock, so that
+// replicas starting together migrate one after the other. SQLite has no
+// advisory locks, its transactions take the single write lock instead, and
+// fn re-reading what was applied is enough.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.sqlDB.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var lock, unlock string
+		switch m.dialect {
+		case "postgres":
+			lock = fmt.Sprintf("SELECT pg_advisory_lock(%d)", lockKey)
+			unlock = fmt.Sprintf("SELECT pg_advisory_unlock(%d)", lockKey)
+		case "mysql":
+			lock = fmt.Sprintf("SELECT GET_LOCK('%s', -1)", lockName)
+			unlock = fmt.Sprintf("SELECT RELEASE_LOCK('%s')", lockName)
+		}
+
+		if lock != "" {
+			if err := conn.Exec(lock).Error; err != nil {
+				return fmt.Errorf("failed to take the migration lock: %w", err)
+			}
+			defer releaseLock(context.WithoutCancel(ctx), conn, unlock)
+		}
+
+		if err := conn.Exec(createSchemaMigrations).Error; err != nil {
+			return fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+		return fn(conn)
+	})
+}
+
+// releaseLock releases the migration lock. The lock belongs to the session, and
+// the connection goes back to the pool rather than being closed, so a lock
+// that wasn't released would block every other replica's migrations for as
+// long as the connection lives. If the unlock fails the connection is
+// discarded instead, closing the session and its locks with it.
+func releaseLock(ctx context.Context, conn *gorm.DB, unlock string) {
+	var released sql.NullBool
+	err := conn.WithContext(ctx).Raw(unlock).Row().Scan(&released)
+	if err == nil && !released.Bool {
+		err = errors.New("the lock was not held")
+	}
+	if err == nil {
+		return
+	}
+	log.Printf("🔴 Failed to release the migration lock, discarding the connection: %v", err)
+	if err := discard(conn); err != nil {
+		log.Printf("🔴 Failed to discard the connection holding the migration lock: %v", err)
+	}
+}
+
+// discard marks the connection conn runs on as broken, so that the pool
+// closes it instead of reusing it once it is released.
+func discard(conn *gorm.DB) error {
+	sqlConn, ok := conn.Statement.ConnPool.(*sql.Conn)
+	if !ok {
+		return fmt.Errorf("not running on a single connection but %T", conn.Statement.ConnPool)
+	}
+	err := sqlConn.Raw(func(any) error { return driver.ErrBadConn })
+	if errors.Is(err, driver.ErrBadConn) {
+		return nil
+	}
+	return err
+}
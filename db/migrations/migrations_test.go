@@ -0,0 +1,209 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openSQLite(t *testing.T, file string) *gorm.DB {
+	sqlDB, err := gorm.Open(sqlite.Open("file:"+file+"?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate"), &gorm.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if conn, err := sqlDB.DB(); err == nil {
+			conn.Close()
+		}
+	})
+	return sqlDB
+}
+
+func newSQLiteMigrator(t *testing.T) (*Migrator, *gorm.DB) {
+	sqlDB := openSQLite(t, filepath.Join(t.TempDir(), "blog.db"))
+	migrator, err := New(sqlDB)
+	require.NoError(t, err)
+	return migrator, sqlDB
+}
+
+func TestEveryDialectHasTheSameMigrations(t *testing.T) {
+	want, err := Load("postgres")
+	require.NoError(t, err)
+	require.NotEmpty(t, want)
+
+	for _, dialect := range []string{"sqlite", "mysql"} {
+		got, err := Load(dialect)
+		require.NoError(t, err)
+		require.Len(t, got, len(want), dialect)
+		for i := range want {
+			require.Equal(t, want[i].String(), got[i].String(), dialect)
+		}
+	}
+
+	_, err = Load("oracle")
+	require.Error(t, err)
+}
+
+func TestStatements(t *testing.T) {
+	script := `-- A comment.
+
+CREATE TABLE "a" (
+    "id" text
+);
+CREATE INDEX "idx" ON "a" ("id");
+DROP TABLE "b"`
+	require.Equal(t, []string{
+		"CREATE TABLE \"a\" (\n    \"id\" text\n);",
+		`CREATE INDEX "idx" ON "a" ("id");`,
+		`DROP TABLE "b"`,
+	}, statements(script))
+}
+
+func TestUpDownAndStatus(t *testing.T) {
+	ctx := context.Background()
+	migrator, sqlDB := newSQLiteMigrator(t)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.False(t, statuses[0].Applied)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, len(migrator.migrations))
+	require.True(t, sqlDB.Migrator().HasTable("posts"))
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, st := range statuses {
+		require.True(t, st.Applied, st.Migration.String())
+		require.False(t, st.AppliedAt.IsZero())
+	}
+
+	reverted, err := migrator.Down(ctx, len(migrator.migrations))
+	require.NoError(t, err)
+	require.Len(t, reverted, len(migrator.migrations))
+	require.False(t, sqlDB.Migrator().HasTable("posts"))
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, len(migrator.migrations))
+}
+
+func TestWebhookEventTypesAreBackfilled(t *testing.T) {
+	ctx := context.Background()
+	migrator, sqlDB := newSQLiteMigrator(t)
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	_, err = migrator.Down(ctx, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, sqlDB.Exec(`INSERT INTO webhooks (id, url, event_types, secret) VALUES
+		('webhook-1', 'https://example.com', 'POST_CREATED,LIKES_CHANGED', 's'),
+		('webhook-2', 'https://example.com', 'POST_UPDATED', 's')`).Error)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	var rows []struct{ EventType, WebhookID string }
+	require.NoError(t, sqlDB.Table("webhook_event_types").Order("webhook_id, event_type").Find(&rows).Error)
+	require.Equal(t, []struct{ EventType, WebhookID string }{
+		{"LIKES_CHANGED", "webhook-1"},
+		{"POST_CREATED", "webhook-1"},
+		{"POST_UPDATED", "webhook-2"},
+	}, rows)
+}
+
+func TestUpRefusesChangedMigrations(t *testing.T) {
+	ctx := context.Background()
+	migrator, sqlDB := newSQLiteMigrator(t)
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sqlDB.Model(&appliedMigration{}).Where("version = ?", 1).Update("checksum", "edited").Error)
+	_, err = migrator.Up(ctx)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	_, err = migrator.Status(ctx)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestNewerMigrationsAreLeftAlone(t *testing.T) {
+	ctx := context.Background()
+	migrator, sqlDB := newSQLiteMigrator(t)
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sqlDB.Create(&appliedMigration{Version: 9999, Name: "from_the_future", Checksum: "x"}).Error)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	last := statuses[len(statuses)-1]
+	require.Equal(t, "9999_from_the_future", last.Migration.String())
+	require.True(t, last.Applied)
+
+	_, err = migrator.Down(ctx, 1)
+	require.Error(t, err)
+}
+
+func TestConcurrentUpAppliesOnce(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "blog.db")
+
+	var wg sync.WaitGroup
+	results := make(chan []Migration, 4)
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		migrator, err := New(openSQLite(t, file))
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied, err := migrator.Up(ctx)
+			results <- applied
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	total := 0
+	for applied := range results {
+		total += len(applied)
+	}
+	migrations, err := Load("sqlite")
+	require.NoError(t, err)
+	require.Equal(t, len(migrations), total)
+}
+
+func TestFailedUnlockDiscardsTheConnection(t *testing.T) {
+	sqlDB := openSQLite(t, filepath.Join(t.TempDir(), "blog.db"))
+	pool, err := sqlDB.DB()
+	require.NoError(t, err)
+
+	require.NoError(t, sqlDB.Connection(func(conn *gorm.DB) error {
+		// SQLite has no advisory locks, a query failing stands in for an
+		// unlock that fails.
+		releaseLock(context.Background(), conn, "SELECT no_such_function()")
+		return nil
+	}))
+	require.Zero(t, pool.Stats().OpenConnections)
+
+	require.NoError(t, sqlDB.Connection(func(conn *gorm.DB) error {
+		releaseLock(context.Background(), conn, "SELECT 1")
+		return nil
+	}))
+	require.Equal(t, 1, pool.Stats().OpenConnections)
+}
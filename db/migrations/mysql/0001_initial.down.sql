@@ -0,0 +1,8 @@
+DROP TABLE `outbox_events`;
+DROP TABLE `webhook_deliveries`;
+DROP TABLE `webhooks`;
+DROP TABLE `notification_actors`;
+DROP TABLE `notifications`;
+DROP TABLE `likes`;
+DROP TABLE `posts`;
+DROP TABLE `users`;
@@ -0,0 +1,95 @@
+-- The schema as AutoMigrate created it, so databases it created are
+-- adopted as they are. MySQL has no CREATE INDEX IF NOT EXISTS, the indexes
+-- are part of the tables.
+
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` varchar(191),
+    `nick_name` varchar(100) NOT NULL,
+    `photo_url` longtext,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `uni_users_nick_name` UNIQUE (`nick_name`)
+);
+
+CREATE TABLE IF NOT EXISTS `posts` (
+    `id` varchar(191),
+    `author_id` varchar(191),
+    `body` longtext NOT NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_posts_author` FOREIGN KEY (`author_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `likes` (
+    `post_id` varchar(191),
+    `user_id` varchar(191),
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`post_id`, `user_id`),
+    INDEX `idx_likes_user_id` (`user_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `notifications` (
+    `id` varchar(191),
+    `recipient_id` varchar(191),
+    `kind` longtext NOT NULL,
+    `post_id` varchar(191),
+    `actor_id` varchar(191),
+    `actor_count` bigint,
+    `read` boolean,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    -- MySQL has no partial indexes: unread_key is NULL once read, and NULLs
+    -- don't collide, leaving at most one unread notification per recipient,
+    -- kind and post.
+    `unread_key` varchar(600) GENERATED ALWAYS AS (IF(`read`, NULL, CONCAT(`recipient_id`, ' ', `kind`, ' ', `post_id`))) STORED,
+    PRIMARY KEY (`id`),
+    INDEX `idx_notifications_recipient` (`recipient_id`, `read`),
+    INDEX `idx_notifications_post_id` (`post_id`),
+    UNIQUE INDEX `idx_notifications_unread` (`unread_key`),
+    CONSTRAINT `fk_notifications_actor` FOREIGN KEY (`actor_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `notification_actors` (
+    `notification_id` varchar(191),
+    `actor_id` varchar(191),
+    PRIMARY KEY (`notification_id`, `actor_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `webhooks` (
+    `id` varchar(191),
+    `owner_id` varchar(191),
+    `url` longtext NOT NULL,
+    `event_types` longtext NOT NULL,
+    `secret` longtext NOT NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_webhooks_owner_id` (`owner_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+    `id` varchar(191),
+    `webhook_id` varchar(191),
+    `event_id` longtext,
+    `event_type` longtext,
+    `payload` longtext NOT NULL,
+    `status` varchar(191) NOT NULL,
+    `next_attempt_at` datetime(3) NULL,
+    `attempts` bigint,
+    `response_code` bigint,
+    `last_error` longtext,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_webhook_deliveries_due` (`status`, `next_attempt_at`),
+    INDEX `idx_webhook_deliveries_webhook_id` (`webhook_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `type` longtext NOT NULL,
+    `post_id` longtext,
+    `payload` longblob NOT NULL,
+    `created_at` datetime(3) NULL,
+    `sent_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_outbox_events_sent_at` (`sent_at`)
+);
@@ -0,0 +1,91 @@
+-- The schema as AutoMigrate created it, so databases it created are
+-- adopted as they are.
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" text,
+    "nick_name" varchar(100) NOT NULL,
+    "photo_url" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_users_nick_name" UNIQUE ("nick_name")
+);
+
+CREATE TABLE IF NOT EXISTS "posts" (
+    "id" text,
+    "author_id" text,
+    "body" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_posts_author" FOREIGN KEY ("author_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "likes" (
+    "post_id" text,
+    "user_id" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("post_id", "user_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_likes_user_id" ON "likes" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "notifications" (
+    "id" text,
+    "recipient_id" text,
+    "kind" text NOT NULL,
+    "post_id" text,
+    "actor_id" text,
+    "actor_count" bigint,
+    "read" boolean,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_notifications_actor" FOREIGN KEY ("actor_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_notifications_post_id" ON "notifications" ("post_id");
+CREATE INDEX IF NOT EXISTS "idx_notifications_recipient" ON "notifications" ("recipient_id", "read");
+-- At most one unread notification per recipient, kind and post.
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_notifications_unread" ON "notifications" ("recipient_id", "kind", "post_id") WHERE NOT "read";
+
+CREATE TABLE IF NOT EXISTS "notification_actors" (
+    "notification_id" text,
+    "actor_id" text,
+    PRIMARY KEY ("notification_id", "actor_id")
+);
+
+CREATE TABLE IF NOT EXISTS "webhooks" (
+    "id" text,
+    "owner_id" text,
+    "url" text NOT NULL,
+    "event_types" text NOT NULL,
+    "secret" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhooks_owner_id" ON "webhooks" ("owner_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "id" text,
+    "webhook_id" text,
+    "event_id" text,
+    "event_type" text,
+    "payload" text NOT NULL,
+    "status" text NOT NULL,
+    "next_attempt_at" timestamptz,
+    "attempts" bigint,
+    "response_code" bigint,
+    "last_error" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_due" ON "webhook_deliveries" ("status", "next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_webhook_id" ON "webhook_deliveries" ("webhook_id");
+
+CREATE TABLE IF NOT EXISTS "outbox_events" (
+    "id" bigserial,
+    "type" text NOT NULL,
+    "post_id" text,
+    "payload" bytea NOT NULL,
+    "created_at" timestamptz,
+    "sent_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_sent_at" ON "outbox_events" ("sent_at");
@@ -0,0 +1,17 @@
+-- One row per webhook and event type it subscribes to, so that the
+-- webhooks of an event are found through the primary key.
+
+CREATE TABLE IF NOT EXISTS "webhook_event_types" (
+    "event_type" text,
+    "webhook_id" text,
+    PRIMARY KEY ("event_type", "webhook_id")
+);
+
+INSERT INTO "webhook_event_types" ("event_type", "webhook_id")
+SELECT 'POST_CREATED', "id" FROM "webhooks" WHERE ',' || "event_types" || ',' LIKE '%,POST_CREATED,%';
+INSERT INTO "webhook_event_types" ("event_type", "webhook_id")
+SELECT 'POST_UPDATED', "id" FROM "webhooks" WHERE ',' || "event_types" || ',' LIKE '%,POST_UPDATED,%';
+INSERT INTO "webhook_event_types" ("event_type", "webhook_id")
+SELECT 'POST_DELETED', "id" FROM "webhooks" WHERE ',' || "event_types" || ',' LIKE '%,POST_DELETED,%';
+INSERT INTO "webhook_event_types" ("event_type", "webhook_id")
+SELECT 'LIKES_CHANGED', "id" FROM "webhooks" WHERE ',' || "event_types" || ',' LIKE '%,LIKES_CHANGED,%';
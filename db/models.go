@@ -11,9 +11,94 @@ type User struct {
 }
 
 type Post struct {
-	ID        string `gorm:"primaryKey"`
-	AuthorID  string
+	ID string `gorm:"primaryKey"`
+	// Sized, MySQL can't reference a users.id from an unsized column.
+	AuthorID  string `gorm:"size:191"`
 	Author    User   `gorm:"foreignKey:AuthorID"`
 	Body      string `gorm:"not null"`
 	CreatedAt time.Time
 }
+
+// Like is the durable record of a user liking a post. Redis keeps a cache of
+// likes that can be rebuilt from this table.
+type Like struct {
+	PostID    string `gorm:"primaryKey"`
+	UserID    string `gorm:"primaryKey;index"`
+	CreatedAt time.Time
+}
+
+// Notification tells a user about activity on their posts. Repeated activity
+// of the same kind on the same post is folded into a single unread
+// notification; once it is read the next activity starts a new one. A unique
+// index, idx_notifications_unread, keeps concurrent activity from starting two.
+type Notification struct {
+	ID          string `gorm:"primaryKey"`
+	RecipientID string `gorm:"index:idx_notifications_recipient"`
+	Kind        string `gorm:"not null"`
+	PostID      string `gorm:"index"`
+	// ActorID is the most recent user behind the notification, ActorCount
+	// how many distinct users there are in total. ActorID is sized for
+	// MySQL like Post.AuthorID.
+	ActorID    string `gorm:"size:191"`
+	Actor      User   `gorm:"foreignKey:ActorID"`
+	ActorCount int
+	Read       bool `gorm:"index:idx_notifications_recipient"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// NotificationActor records who is counted in a notification, so that the
+// same user liking, unliking and liking again is only counted once.
+type NotificationActor struct {
+	NotificationID string `gorm:"primaryKey"`
+	ActorID        string `gorm:"primaryKey"`
+}
+
+// Webhook subscribes a URL to blog events. EventTypes is a comma separated
+// list of event type names, e.g. "POST_CREATED,LIKES_CHANGED".
+type Webhook struct {
+	ID         string `gorm:"primaryKey"`
+	OwnerID    string `gorm:"index"`
+	URL        string `gorm:"not null"`
+	EventTypes string `gorm:"not null"`
+	Secret     string `gorm:"not null"`
+	CreatedAt  time.Time
+}
+
+// WebhookEventType subscribes a webhook to one event type. It mirrors
+// Webhook.EventTypes so that the webhooks of an event are found through the
+// primary key.
+type WebhookEventType struct {
+	EventType string `gorm:"primaryKey"`
+	WebhookID string `gorm:"primaryKey"`
+}
+
+// WebhookDelivery is one event to be sent to one webhook, kept as the
+// delivery log. NextAttemptAt is when a pending delivery is due next.
+type WebhookDelivery struct {
+	ID            string `gorm:"primaryKey"`
+	WebhookID     string `gorm:"index"`
+	EventID       string
+	EventType     string
+	Payload       string    `gorm:"not null"`
+	Status        string    `gorm:"index:idx_webhook_deliveries_due;not null"`
+	NextAttemptAt time.Time `gorm:"index:idx_webhook_deliveries_due"`
+	Attempts      int
+	ResponseCode  int
+	LastError     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// OutboxEvent is a domain event written in the same transaction as the change
+// it describes, so the change and its event are stored together or not at
+// all. Payload is the encoded blog.FeedEvent; SentAt is set once the relay
+// has published it.
+type OutboxEvent struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	Type      string `gorm:"not null"`
+	PostID    string
+	Payload   []byte `gorm:"not null"`
+	CreatedAt time.Time
+	SentAt    *time.Time `gorm:"index"`
+}
@@ -0,0 +1,252 @@
+// Package gateway holds HTTP endpoints served on the gateway next to the
+// generated REST routes.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	blog "go_grpc_blog/api"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	defaultStreamBuffer       = 64
+	defaultStreamWriteTimeout = 10 * time.Second
+
+	slowConsumerReason = "slow consumer: events were not read fast enough, reconnect with last_event_id"
+)
+
+// FeedStream relays WatchFeed events to browsers as Server-Sent Events or
+// over a WebSocket. Each connection gets a bounded buffer; a client that lets
+// it fill up is disconnected with a close reason instead of slowing down the
+// stream for everyone else.
+type FeedStream struct {
+	client       blog.BlogServiceClient
+	upgrader     websocket.Upgrader
+	marshaler    protojson.MarshalOptions
+	Buffer       int
+	WriteTimeout time.Duration
+}
+
+func NewFeedStream(client blog.BlogServiceClient) *FeedStream {
+	return &FeedStream{
+		client: client,
+		upgrader: websocket.Upgrader{
+			// Identity comes from a header or query parameter, never from
+			// cookies, so cross-origin pages can't act on a user's behalf.
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		Buffer:       defaultStreamBuffer,
+		WriteTimeout: defaultStreamWriteTimeout,
+	}
+}
+
+// relay is one running WatchFeed call. events is closed once the call ended,
+// after which err tells why. slow is closed as well if the client was dropped
+// for falling behind, in which case the buffered events are not worth sending.
+type relay struct {
+	events chan *blog.FeedEvent
+	slow   chan struct{}
+	err    error
+	cancel context.CancelFunc
+}
+
+// next returns the next event to send, or false once the client should be
+// told why the stream ended.
+func (rl *relay) next() (*blog.FeedEvent, bool) {
+	event, ok := <-rl.events
+	if !ok {
+		return nil, false
+	}
+	select {
+	case <-rl.slow:
+		return nil, false
+	default:
+		return event, true
+	}
+}
+
+// userID reads the caller the same way the REST routes do, from the
+// Grpc-Metadata-User-Id header. Browsers can't set headers on EventSource or
+// WebSocket requests, so the user_id query parameter is accepted as well.
+func userID(r *http.Request) string {
+	if id := r.Header.Get("Grpc-Metadata-User-Id"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("user_id")
+}
+
+// lastEventID honours the Last-Event-ID header that EventSource sends when it
+// reconnects on its own.
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("last_event_id")
+}
+
+func (f *FeedStream) watch(ctx context.Context, userID, lastEventID string) *relay {
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(ctx, "user-id", userID))
+	rl := &relay{
+		events: make(chan *blog.FeedEvent, f.Buffer),
+		slow:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer close(rl.events)
+		defer cancel()
+
+		stream, err := f.client.WatchFeed(ctx, &blog.WatchFeedRequest{LastEventId: lastEventID})
+		if err != nil {
+			rl.err = err
+			return
+		}
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				rl.err = err
+				return
+			}
+			select {
+			case rl.events <- event:
+			default:
+				rl.err = status.Error(codes.ResourceExhausted, slowConsumerReason)
+				close(rl.slow)
+				return
+			}
+		}
+	}()
+
+	return rl
+}
+
+// closeReason is the message sent to the client when the relay ends.
+func closeReason(err error) string {
+	if st, ok := status.FromError(err); ok {
+		return st.Message()
+	}
+	return err.Error()
+}
+
+func (f *FeedStream) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	user := userID(r)
+	if user == "" {
+		http.Error(w, "user-id header is required", http.StatusUnauthorized)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	rl := f.watch(r.Context(), user, lastEventID(r))
+	defer rl.cancel()
+
+	write := func(format string, args ...any) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(f.WriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	for {
+		event, ok := rl.next()
+		if !ok {
+			break
+		}
+		var err error
+		if event.Type == blog.FeedEvent_HEARTBEAT {
+			err = write(": heartbeat\n\n")
+		} else {
+			data, _ := f.marshaler.Marshal(event)
+			err = write("id: %s\nevent: %s\ndata: %s\n\n", event.Id, strings.ToLower(event.Type.String()), data)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	// Wait for the reason in case the loop stopped on a slow client.
+	for range rl.events {
+	}
+	if r.Context().Err() == nil {
+		_ = write("event: close\ndata: %s\n\n", closeReason(rl.err))
+	}
+}
+
+func (f *FeedStream) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	user := userID(r)
+	if user == "" {
+		http.Error(w, "user-id header is required", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := f.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	rl := f.watch(r.Context(), user, lastEventID(r))
+	defer rl.cancel()
+
+	// The client only sends control frames; reading them is what notices a
+	// closed connection.
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				rl.cancel()
+				return
+			}
+		}
+	}()
+
+	for {
+		event, ok := rl.next()
+		if !ok {
+			break
+		}
+		data, _ := f.marshaler.Marshal(event)
+		_ = conn.SetWriteDeadline(time.Now().Add(f.WriteTimeout))
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+	}
+
+	for range rl.events {
+	}
+	code := websocket.CloseInternalServerErr
+	switch status.Code(rl.err) {
+	case codes.ResourceExhausted:
+		code = websocket.CloseTryAgainLater
+	case codes.Unauthenticated, codes.PermissionDenied, codes.InvalidArgument, codes.OutOfRange:
+		code = websocket.ClosePolicyViolation
+	case codes.Canceled:
+		code = websocket.CloseGoingAway
+	}
+	reason := closeReason(rl.err)
+	// Close frame payloads are limited to 125 bytes.
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(f.WriteTimeout))
+}
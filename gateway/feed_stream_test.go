@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	blog "go_grpc_blog/api"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeFeedClient serves WatchFeed from a channel of events.
+type fakeFeedClient struct {
+	blog.BlogServiceClient
+	events chan *blog.FeedEvent
+	userID string
+}
+
+type fakeFeedStream struct {
+	grpc.ServerStreamingClient[blog.FeedEvent]
+	ctx    context.Context
+	events chan *blog.FeedEvent
+}
+
+func (c *fakeFeedClient) WatchFeed(ctx context.Context, _ *blog.WatchFeedRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[blog.FeedEvent], error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if ids := md.Get("user-id"); len(ids) > 0 {
+		c.userID = ids[0]
+	}
+	return &fakeFeedStream{ctx: ctx, events: c.events}, nil
+}
+
+func (s *fakeFeedStream) Recv() (*blog.FeedEvent, error) {
+	select {
+	case event, ok := <-s.events:
+		if !ok {
+			return nil, status.Error(codes.Unavailable, "server is shutting down")
+		}
+		return event, nil
+	case <-s.ctx.Done():
+		return nil, status.FromContextError(s.ctx.Err()).Err()
+	}
+}
+
+func TestServeSSERelaysEventsAndCloseReason(t *testing.T) {
+	client := &fakeFeedClient{events: make(chan *blog.FeedEvent, 3)}
+	client.events <- &blog.FeedEvent{Id: "1-0", Type: blog.FeedEvent_POST_CREATED, PostId: "post-1"}
+	client.events <- &blog.FeedEvent{Type: blog.FeedEvent_HEARTBEAT}
+	close(client.events)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/stream/feed", nil)
+	req.Header.Set("Grpc-Metadata-User-Id", "user-1")
+	rec := httptest.NewRecorder()
+	NewFeedStream(client).ServeSSE(rec, req)
+
+	body, _ := io.ReadAll(rec.Body)
+	require.Equal(t, "user-1", client.userID)
+	require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	require.Contains(t, string(body), "id: 1-0\nevent: post_created\ndata: {")
+	require.Contains(t, string(body), ": heartbeat\n\n")
+	require.True(t, strings.HasSuffix(string(body), "event: close\ndata: server is shutting down\n\n"))
+}
+
+func TestServeSSERequiresUser(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewFeedStream(&fakeFeedClient{}).ServeSSE(rec, httptest.NewRequest(http.MethodGet, "/v1/stream/feed", nil))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestRelayDropsSlowConsumer(t *testing.T) {
+	client := &fakeFeedClient{events: make(chan *blog.FeedEvent)}
+	stream := NewFeedStream(client)
+	stream.Buffer = 2
+
+	rl := stream.watch(context.Background(), "user-1", "")
+	defer rl.cancel()
+	for i := 0; i < 3; i++ {
+		client.events <- &blog.FeedEvent{Type: blog.FeedEvent_LIKES_CHANGED}
+	}
+
+	select {
+	case <-rl.slow:
+	case <-time.After(time.Second):
+		t.Fatal("slow consumer was not dropped")
+	}
+	_, ok := rl.next()
+	require.False(t, ok)
+	require.Equal(t, codes.ResourceExhausted, status.Code(rl.err))
+}
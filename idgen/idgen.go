@@ -0,0 +1,28 @@
+// Package idgen generates collision-free identifiers that sort by creation time.
+package idgen
+
+import (
+	"fmt"
+)
+
+// Generator produces unique identifiers. IDs returned by the same generator
+// sort lexicographically in the order they were created.
+type Generator interface {
+	NewID() string
+}
+
+// Default is used by callers that were not given a generator explicitly.
+var Default Generator = NewULID()
+
+// New returns the generator registered under kind ("ulid" or "snowflake").
+// nodeID is only used by the snowflake generator and must be unique per replica.
+func New(kind string, nodeID int64) (Generator, error) {
+	switch kind {
+	case "", "ulid":
+		return NewULID(), nil
+	case "snowflake":
+		return NewSnowflake(nodeID)
+	default:
+		return nil, fmt.Errorf("unknown id generator %q", kind)
+	}
+}
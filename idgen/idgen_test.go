@@ -0,0 +1,71 @@
+package idgen
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func generators(t *testing.T) map[string]Generator {
+	snowflake, err := NewSnowflake(7)
+	require.NoError(t, err)
+	return map[string]Generator{
+		"ulid":      NewULID(),
+		"snowflake": snowflake,
+	}
+}
+
+func TestIDsSortByCreationOrder(t *testing.T) {
+	for name, gen := range generators(t) {
+		t.Run(name, func(t *testing.T) {
+			ids := make([]string, 10000)
+			for i := range ids {
+				ids[i] = gen.NewID()
+			}
+			require.True(t, sort.StringsAreSorted(ids))
+			for i := 1; i < len(ids); i++ {
+				require.NotEqual(t, ids[i-1], ids[i])
+			}
+		})
+	}
+}
+
+func TestIDsAreUniqueAcrossGoroutines(t *testing.T) {
+	for name, gen := range generators(t) {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var wg sync.WaitGroup
+			seen := make(map[string]bool)
+			duplicates := 0
+
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 1000; i++ {
+						id := gen.NewID()
+						mu.Lock()
+						if seen[id] {
+							duplicates++
+						}
+						seen[id] = true
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			require.Zero(t, duplicates)
+			require.Len(t, seen, 8000)
+		})
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	_, err := New("uuid", 0)
+	require.Error(t, err)
+
+	_, err = New("snowflake", MaxNodeID+1)
+	require.Error(t, err)
+}
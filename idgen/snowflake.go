@@ -0,0 +1,80 @@
+package idgen
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+
+	MaxNodeID   = 1<<nodeBits - 1
+	maxSequence = 1<<sequenceBits - 1
+
+	// encodedLen is the number of base32 characters needed for 63 bits.
+	encodedLen = 13
+	alphabet   = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// epoch is the custom snowflake epoch, which leaves 41 bits of milliseconds
+// enough room for roughly 69 years.
+var epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates 63-bit IDs made of a millisecond timestamp, a node ID
+// and a per-millisecond sequence. They are encoded as fixed-width Crockford
+// base32 so that string order matches numeric order.
+type Snowflake struct {
+	mu       sync.Mutex
+	nodeID   int64
+	lastMs   int64
+	sequence int64
+}
+
+func NewSnowflake(nodeID int64) (*Snowflake, error) {
+	if nodeID < 0 || nodeID > MaxNodeID {
+		return nil, fmt.Errorf("snowflake node id must be between 0 and %d, got %d", MaxNodeID, nodeID)
+	}
+	return &Snowflake{nodeID: nodeID}, nil
+}
+
+func (g *Snowflake) NewID() string {
+	return encode(g.next())
+}
+
+func (g *Snowflake) next() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Since(epoch).Milliseconds()
+	// Never go back in time: a clock step backwards keeps using the last
+	// millisecond until the sequence runs out.
+	if now < g.lastMs {
+		now = g.lastMs
+	}
+
+	if now == g.lastMs {
+		g.sequence = (g.sequence + 1) & maxSequence
+		if g.sequence == 0 {
+			for now <= g.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Since(epoch).Milliseconds()
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = now
+
+	return now<<(nodeBits+sequenceBits) | g.nodeID<<sequenceBits | g.sequence
+}
+
+func encode(id int64) string {
+	var buf [encodedLen]byte
+	for i := encodedLen - 1; i >= 0; i-- {
+		buf[i] = alphabet[id&31]
+		id >>= 5
+	}
+	return string(buf[:])
+}
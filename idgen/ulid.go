@@ -0,0 +1,27 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// ULID generates 26 character ULIDs. IDs created within the same millisecond
+// are monotonically increasing, so ordering holds even under heavy load.
+type ULID struct {
+	mu      sync.Mutex
+	entropy *ulid.MonotonicEntropy
+}
+
+func NewULID() *ULID {
+	return &ULID{entropy: ulid.Monotonic(rand.Reader, 0)}
+}
+
+func (g *ULID) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return ulid.MustNew(ulid.Timestamp(time.Now()), g.entropy).String()
+}
@@ -2,23 +2,36 @@ package main
 
 import (
 	"context"
+	"embed"
+	"flag"
+	"fmt"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
-	"embed"
-
 	blog "go_grpc_blog/api"
+	blogv2 "go_grpc_blog/api/v2"
 	server "go_grpc_blog/cmd"
 	db "go_grpc_blog/db"
+	"go_grpc_blog/db/fixtures"
+	"go_grpc_blog/db/migrations"
+	"go_grpc_blog/gateway"
+	"go_grpc_blog/idgen"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"gorm.io/gorm"
 )
 
 //go:embed api/api.swagger.json
@@ -27,29 +40,178 @@ var swaggerData []byte
 //go:embed swagger-ui
 var swaggerFiles embed.FS
 
+var (
+	databaseURL   = flag.String("database", "postgres://localhost:5432/postgres?sslmode=disable&TimeZone=UTC", "database url: postgres://…, mysql://… or sqlite://<file>")
+	idGenerator   = flag.String("id-generator", "ulid", "generator for new entity ids: ulid or snowflake")
+	nodeID        = flag.Int64("node-id", 0, "snowflake node id, must be unique per replica")
+	feedHeartbeat = flag.Duration("feed-heartbeat", 15*time.Second, "interval of WatchFeed heartbeats on an idle feed")
+	webhookTries  = flag.Int("webhook-max-attempts", 8, "attempts before a webhook delivery is dead-lettered")
+	webhookLocal  = flag.Bool("webhook-allow-private", false, "let webhooks target loopback, link-local and private addresses, for local development only")
+	cacheKind     = flag.String("cache", server.CacheRedis, "backend caching the feed and likes: redis, memory (single replica only) or none")
+	cacheRefresh  = flag.Duration("cache-refresh", time.Minute, "interval of the safety-net feed cache refresh, post changes invalidate it right away")
+	nearSize      = flag.Int("near-cache-size", server.DefaultNearCacheSize, "posts kept in the in-process cache in front of redis, 0 disables it")
+	cacheCodec    = flag.String("cache-codec", "json", "encoding of cached posts: json or proto, optionally compressed with +zstd or +snappy")
+	redisMode     = flag.String("redis-mode", server.RedisSingle, "redis deployment: single, sentinel or cluster")
+	redisAddrs    = flag.String("redis-addrs", "127.0.0.1:6379", "comma-separated redis node, sentinel or cluster seed addresses")
+	redisMaster   = flag.String("redis-master", "", "name of the master monitored by the sentinels")
+	nearTTL       = flag.Duration("near-cache-ttl", server.DefaultNearCacheTTL, "how long the in-process cache keeps an entry")
+	seedSets      = flag.String("seed", "", "comma-separated fixture sets or files seeded at startup, e.g. demo; nothing is seeded by default")
+)
+
 func main() {
-	sql_db, err := db.InitDB("host=localhost dbname=postgres port=5432 sslmode=disable TimeZone=UTC")
+	flag.Parse()
+
+	if flag.Arg(0) == "migrate" {
+		if err := migrate(flag.Args()[1:]); err != nil {
+			log.Fatalf("🔴 %v", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "seed" {
+		if err := seedCommand(flag.Args()[1:]); err != nil {
+			log.Fatalf("🔴 %v", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "generate" {
+		if err := generate(flag.Args()[1:]); err != nil {
+			log.Fatalf("🔴 %v", err)
+		}
+		return
+	}
+
+	if *feedHeartbeat <= 0 || *cacheRefresh <= 0 {
+		log.Fatalf("🔴 -feed-heartbeat and -cache-refresh must be positive")
+	}
+
+	ids, err := idgen.New(*idGenerator, *nodeID)
+	if err != nil {
+		log.Fatalf("🔴 Failed to initialize id generator: %v", err)
+	}
+
+	sql_db, err := db.InitDB(*databaseURL)
 	if err != nil {
 		log.Fatalf("🔴 Failed to initialize sql database: %v", err)
 	}
-	log.Println("🟢 Starting SQL DB  on port 5432")
+	log.Printf("🟢 Connected to %s database", db.DialectOf(sql_db).Name)
 
-	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	var seeded []string
+	if *seedSets != "" {
+		if seeded, err = seed(sql_db, strings.Split(*seedSets, ",")); err != nil {
+			log.Fatalf("🔴 %v", err)
+		}
+	}
 
+	// Only the redis cache needs Redis, the other kinds run on one replica
+	// without it.
 	ctx := context.Background()
-	_, err = rdb.Ping(ctx).Result()
+	var rdb redis.UniversalClient
+	var breaker *server.Breaker
+	var redisErr error
+	if *cacheKind == server.CacheRedis {
+		if rdb, err = newRedisClient(); err != nil {
+			log.Fatalf("🔴 Failed to configure redis: %v", err)
+		}
+		breaker = server.NewBreaker()
+		server.GuardRedis(rdb, breaker)
+
+		if redisErr = rdb.Ping(ctx).Err(); redisErr == nil {
+			log.Printf("🟢 Connected to %s Redis at %s", *redisMode, *redisAddrs)
+		}
+	}
+
+	codec, err := server.ParseCacheCodec(*cacheCodec)
 	if err != nil {
-		log.Fatalf("🔴 Failed to initialize redis database: %v", err)
+		log.Fatalf("🔴 Failed to initialize cache codec: %v", err)
+	}
+	feedCache, err := server.NewFeedCache(*cacheKind, rdb, codec)
+	if err != nil {
+		log.Fatalf("🔴 Failed to initialize feed cache: %v", err)
+	}
+	likeStore, err := server.NewLikeStore(*cacheKind, rdb)
+	if err != nil {
+		log.Fatalf("🔴 Failed to initialize like store: %v", err)
+	}
+
+	var near *server.NearCache
+	if *cacheKind == server.CacheRedis && *nearSize > 0 {
+		near = server.NewNearCache(feedCache, rdb, *nearSize, *nearTTL)
+		near.Codec = codec
+		feedCache = near
+		go near.Run(ctx)
 	}
-	log.Println("🟢 Starting Redis DB on port 6379")
 
 	s := &server.Server{
 		Sql_DB:   sql_db,
 		Redis_DB: rdb,
+		IDs:      ids,
+		Cache:    feedCache,
+		Likes:    likeStore,
+		Near:     near,
+		Feed:     server.NewFeedHub(rdb),
+	}
+	s.Feed.Heartbeat = *feedHeartbeat
+	go s.Feed.Run(ctx)
+
+	// The like keys are migrated and synced once Redis can be reached, which
+	// may only be after startup.
+	var likesMu sync.Mutex
+	likesPrepared := false
+	prepareLikes := func() {
+		likesMu.Lock()
+		defer likesMu.Unlock()
+		if likesPrepared {
+			return
+		}
+		if err := server.PrepareLikes(s, context.Background()); err != nil {
+			log.Printf("🔴 %v", err)
+			return
+		}
+		likesPrepared = true
+	}
+
+	if breaker != nil {
+		breaker.OnChange = func(open bool) {
+			if open {
+				log.Println("🔴 Redis unavailable, serving degraded")
+				return
+			}
+			log.Println("🟢 Redis is back")
+			// Posts changed meanwhile didn't invalidate the feed cache.
+			if err := server.InvalidateCache(s, context.Background()); err != nil {
+				log.Printf("🔴 %v", err)
+			}
+			// OnChange runs inside the Redis command that closed the breaker.
+			go prepareLikes()
+		}
+	}
+	if redisErr != nil {
+		log.Printf("🔴 Failed to connect to Redis at %s, starting degraded: %v", *redisAddrs, redisErr)
+		breaker.Trip()
+	}
+	health := server.NewHealth(sql_db, rdb, breaker)
+	go health.Run(ctx)
+
+	webhooks := server.NewWebhookDispatcher(sql_db, rdb)
+	webhooks.IDs = ids
+	webhooks.MaxAttempts = *webhookTries
+	webhooks.AllowPrivateTargets = *webhookLocal
+	go webhooks.Run(ctx)
+
+	s.Outbox = server.NewOutboxRelay(sql_db, s.Feed, webhooks)
+	go s.Outbox.Run(ctx)
+
+	if len(seeded) > 0 {
+		if err := server.InvalidatePosts(s, ctx, seeded); err != nil {
+			log.Printf("🔴 %v", err)
+		}
+	}
+	if rdb != nil && redisErr == nil {
+		prepareLikes()
 	}
 
 	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
+		ticker := time.NewTicker(*cacheRefresh)
 		defer ticker.Stop()
 
 		for {
@@ -69,6 +231,12 @@ func main() {
 
 	grpcServer := grpc.NewServer()
 	blog.RegisterBlogServiceServer(grpcServer, s)
+	blogv2.RegisterBlogServiceServer(grpcServer, server.NewServerV2(s))
+	blog.RegisterNotificationServiceServer(grpcServer, server.NewNotificationServer(s))
+	webhookServer := server.NewWebhookServer(s)
+	webhookServer.AllowPrivateTargets = *webhookLocal
+	blog.RegisterWebhookServiceServer(grpcServer, webhookServer)
+	healthpb.RegisterHealthServer(grpcServer, health.GRPC)
 	reflection.Register(grpcServer)
 
 	go func() {
@@ -84,9 +252,19 @@ func main() {
 		log.Fatalln("🔴 Failed to dial server:", err)
 	}
 	blog.RegisterBlogServiceHandler(context.Background(), gwmux, conn)
+	blogv2.RegisterBlogServiceHandler(context.Background(), gwmux, conn)
+	blog.RegisterNotificationServiceHandler(context.Background(), gwmux, conn)
+	blog.RegisterWebhookServiceHandler(context.Background(), gwmux, conn)
 
 	mux := http.NewServeMux()
 	mux.Handle("/", gwmux)
+
+	feedStream := gateway.NewFeedStream(blog.NewBlogServiceClient(conn))
+	mux.HandleFunc("/v1/stream/feed", feedStream.ServeSSE)
+	mux.HandleFunc("/v1/stream/feed/ws", feedStream.ServeWebSocket)
+	mux.Handle("/debug/vars", server.CacheStatsHandler())
+	mux.Handle("/healthz", health)
+
 	mux.HandleFunc("/swagger-ui/swagger.json", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write(swaggerData)
 	})
@@ -106,3 +284,233 @@ func main() {
 	log.Println("🟢 Serving gRPC-Gateway on http://0.0.0.0:8090")
 	log.Fatalln(gwServer.ListenAndServe())
 }
+
+func newRedisClient() (redis.UniversalClient, error) {
+	return server.NewRedisClient(server.RedisConfig{
+		Mode:       *redisMode,
+		Addrs:      strings.Split(*redisAddrs, ","),
+		MasterName: *redisMaster,
+		Password:   os.Getenv("REDIS_PASSWORD"),
+	})
+}
+
+// seed upserts fixture sets or files and returns the ids of the seeded posts.
+func seed(sqlDB *gorm.DB, names []string) ([]string, error) {
+	var postIDs []string
+	for _, name := range names {
+		f, err := fixtures.Load(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		if err := fixtures.Seed(context.Background(), sqlDB, f); err != nil {
+			return nil, fmt.Errorf("failed to seed %s: %w", name, err)
+		}
+		log.Printf("🟢 Seeded %s: %d users, %d posts, %d likes", name, len(f.Users), len(f.Posts), len(f.Likes))
+		postIDs = append(postIDs, f.PostIDs()...)
+	}
+	return postIDs, nil
+}
+
+// seedCommand runs the seed command: seed <set|file|dir>... Afterwards the
+// seeded posts are dropped from the Redis cache, if it can be reached.
+func seedCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: seed <set|file|dir>..., the built-in sets are %s", strings.Join(fixtures.Sets(), ", "))
+	}
+
+	sqlDB, err := db.InitDB(*databaseURL)
+	if err != nil {
+		return err
+	}
+	seeded, err := seed(sqlDB, args)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	s, err := cacheServer(ctx, sqlDB)
+	if s == nil || err != nil {
+		return err
+	}
+	return server.InvalidatePosts(s, ctx, seeded)
+}
+
+// cacheServer returns a server with just the Redis feed cache and like store,
+// for commands that write behind the server's back. It returns nil when the
+// cache isn't Redis, or when Redis can't be reached, which is only logged.
+func cacheServer(ctx context.Context, sqlDB *gorm.DB) (*server.Server, error) {
+	if *cacheKind != server.CacheRedis {
+		return nil, nil
+	}
+	rdb, err := newRedisClient()
+	if err == nil {
+		err = rdb.Ping(ctx).Err()
+	}
+	if err != nil {
+		log.Printf("🔴 Redis unavailable, the cache may serve stale data: %v", err)
+		return nil, nil
+	}
+
+	codec, err := server.ParseCacheCodec(*cacheCodec)
+	if err != nil {
+		return nil, err
+	}
+	feedCache, err := server.NewFeedCache(*cacheKind, rdb, codec)
+	if err != nil {
+		return nil, err
+	}
+	likeStore, err := server.NewLikeStore(*cacheKind, rdb)
+	if err != nil {
+		return nil, err
+	}
+	return &server.Server{Sql_DB: sqlDB, Redis_DB: rdb, Cache: feedCache, Likes: likeStore}, nil
+}
+
+// generate runs the generate command, which bulk inserts synthetic users,
+// posts and likes for load testing into the database, and their likes into
+// Redis.
+func generate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	cfg := fixtures.GenerateConfig{}
+	flags.IntVar(&cfg.Users, "users", 10000, "users to generate")
+	flags.IntVar(&cfg.Posts, "posts", 100000, "posts to generate")
+	flags.IntVar(&cfg.Likes, "likes", 500000, "likes to generate, roughly")
+	from := flags.String("from", "2024-01-01", "time of the oldest post, a date or RFC 3339 time")
+	to := flags.String("to", "2025-01-01", "time of the newest post or like, a date or RFC 3339 time")
+	flags.Float64Var(&cfg.AuthorSkew, "author-skew", 1.2, "power law exponent of posts per author, above 1")
+	flags.Float64Var(&cfg.LikeSkew, "like-skew", 1.2, "power law exponent of likes per post and per liker, above 1")
+	flags.Int64Var(&cfg.Seed, "random-seed", 1, "random seed, the same flags generate the same data")
+	flags.IntVar(&cfg.BatchSize, "batch", 5000, "users or posts written per transaction and pipeline")
+	reset := flags.Bool("reset", false, "delete previously generated data first, e.g. what a failed run left behind")
+	flags.Parse(args)
+
+	var err error
+	if cfg.From, err = parseTime(*from); err != nil {
+		return err
+	}
+	if cfg.To, err = parseTime(*to); err != nil {
+		return err
+	}
+
+	sqlDB, err := db.InitDB(*databaseURL)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	s, err := cacheServer(ctx, sqlDB)
+	if err != nil {
+		return err
+	}
+	if *reset {
+		deleted, err := fixtures.DeleteGenerated(ctx, sqlDB)
+		if err != nil {
+			return err
+		}
+		log.Printf("🟢 Deleted %d generated posts", len(deleted))
+		if s != nil {
+			if err := server.InvalidatePosts(s, ctx, deleted); err != nil {
+				return err
+			}
+		}
+	}
+	var exists int64
+	if err := sqlDB.Model(&db.User{}).Where("id = ?", fixtures.GeneratedUserID(0)).Count(&exists).Error; err != nil {
+		return err
+	}
+	if exists > 0 {
+		return fmt.Errorf("the database already holds generated data, rerun with -reset to replace it")
+	}
+
+	start := time.Now()
+	var users, posts, likes int
+	err = fixtures.Generate(cfg, func(f *fixtures.Fixtures) error {
+		if err := fixtures.Insert(ctx, sqlDB, f); err != nil {
+			return err
+		}
+		if s != nil && len(f.Posts) > 0 {
+			_, _, dbLikes := f.Models()
+			if err := server.LoadLikes(ctx, s.Redis_DB, f.PostIDs(), dbLikes); err != nil {
+				return err
+			}
+		}
+		users, posts, likes = users+len(f.Users), posts+len(f.Posts), likes+len(f.Likes)
+		log.Printf("🟢 Generated %d/%d users, %d/%d posts, %d likes", users, cfg.Users, posts, cfg.Posts, likes)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("🟢 Generated %d users, %d posts and %d likes in %s", users, posts, likes, time.Since(start).Round(time.Millisecond))
+
+	if s == nil {
+		return nil
+	}
+	return server.InvalidateCache(s, ctx)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, want a date or RFC 3339 time", value)
+	}
+	return t, nil
+}
+
+// migrate runs the migrate command: up, down [steps] or status.
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status")
+	}
+
+	sqlDB, err := db.Open(*databaseURL)
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("🟢 Applied migration %s", m)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Println("🟢 No pending migrations")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("🟢 Reverted migration %s", m)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tSTATUS")
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\n", st.Migration, state)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown migrate command %q, want up, down or status", args[0])
+}